@@ -0,0 +1,176 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go-task-service/core/mq"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 批量重新投递单次最多处理的条数
+const maxBulkReplay = 1000
+
+// deadLetterError 把死信错误转换为对应的 HTTP 状态码
+func deadLetterError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mq.ErrDeadLetterNotFound):
+		Fail(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, mq.ErrDeadLetterState):
+		Fail(c, http.StatusConflict, err.Error())
+	default:
+		Fail(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// deadLetterFilterReq 批量操作的过滤条件，时间格式为 RFC3339
+type deadLetterFilterReq struct {
+	IDs    []string   `json:"ids"`
+	Topic  string     `json:"topic"`
+	Source string     `json:"source"`
+	Since  *time.Time `json:"since"`
+	Until  *time.Time `json:"until"`
+	Limit  int64      `json:"limit"`
+	Reason string     `json:"reason"`
+}
+
+func (r deadLetterFilterReq) filter() mq.DeadLetterFilter {
+	return mq.DeadLetterFilter{
+		IDs:    r.IDs,
+		Topic:  r.Topic,
+		Source: r.Source,
+		Since:  r.Since,
+		Until:  r.Until,
+	}
+}
+
+// 批量操作至少需要一个过滤条件，避免误操作全部死信
+func (r deadLetterFilterReq) empty() bool {
+	return len(r.IDs) == 0 && r.Topic == "" && r.Source == "" && r.Since == nil && r.Until == nil
+}
+
+// parseTimeQuery 解析 RFC3339 格式的时间参数，参数为空时返回 nil
+func parseTimeQuery(c *gin.Context, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errors.New(key + " 需为 RFC3339 格式")
+	}
+	return &t, nil
+}
+
+// ListDeadLetters 分页查询死信，可按 topic、source、status 和创建时间过滤
+func ListDeadLetters(c *gin.Context) {
+	since, err := parseTimeQuery(c, "since")
+	if err != nil {
+		Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	until, err := parseTimeQuery(c, "until")
+	if err != nil {
+		Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit <= 0 || limit > 500 {
+		Fail(c, http.StatusBadRequest, "limit 取值范围为 1-500")
+		return
+	}
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		Fail(c, http.StatusBadRequest, "offset 不能小于 0")
+		return
+	}
+	docs, total, err := mq.ListDeadLetters(c.Request.Context(), mq.DeadLetterFilter{
+		Topic:  c.Query("topic"),
+		Source: c.Query("source"),
+		Status: c.Query("status"),
+		Since:  since,
+		Until:  until,
+	}, offset, limit)
+	if err != nil {
+		Fail(c, http.StatusInternalServerError, "查询死信失败: "+err.Error())
+		return
+	}
+	Success(c, gin.H{"total": total, "items": docs})
+}
+
+// GetDeadLetter 查询一条死信的完整内容
+func GetDeadLetter(c *gin.Context) {
+	doc, err := mq.GetDeadLetter(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		deadLetterError(c, err)
+		return
+	}
+	Success(c, doc)
+}
+
+// ReplayDeadLetter 重新投递一条死信
+func ReplayDeadLetter(c *gin.Context) {
+	if err := mq.ReplayDeadLetter(c.Request.Context(), c.Param("id")); err != nil {
+		deadLetterError(c, err)
+		return
+	}
+	Success(c, nil)
+}
+
+type discardReq struct {
+	Reason string `json:"reason"`
+}
+
+// DiscardDeadLetter 丢弃一条死信
+func DiscardDeadLetter(c *gin.Context) {
+	var req discardReq
+	// 请求体可以为空
+	_ = c.ShouldBindJSON(&req)
+	if err := mq.DiscardDeadLetter(c.Request.Context(), c.Param("id"), req.Reason); err != nil {
+		deadLetterError(c, err)
+		return
+	}
+	Success(c, nil)
+}
+
+// ReplayDeadLetters 按过滤条件批量重新投递待处理的死信
+func ReplayDeadLetters(c *gin.Context) {
+	var req deadLetterFilterReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Fail(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	if req.empty() {
+		Fail(c, http.StatusBadRequest, "至少需要一个过滤条件")
+		return
+	}
+	if req.Limit <= 0 || req.Limit > maxBulkReplay {
+		req.Limit = maxBulkReplay
+	}
+	result, err := mq.ReplayDeadLetters(c.Request.Context(), req.filter(), req.Limit)
+	if err != nil {
+		Fail(c, http.StatusInternalServerError, "批量重新投递失败: "+err.Error())
+		return
+	}
+	Success(c, result)
+}
+
+// DiscardDeadLetters 按过滤条件批量丢弃待处理的死信
+func DiscardDeadLetters(c *gin.Context) {
+	var req deadLetterFilterReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Fail(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	if req.empty() {
+		Fail(c, http.StatusBadRequest, "至少需要一个过滤条件")
+		return
+	}
+	n, err := mq.DiscardDeadLettersByFilter(c.Request.Context(), req.filter(), req.Reason)
+	if err != nil {
+		Fail(c, http.StatusBadRequest, "批量丢弃失败: "+err.Error())
+		return
+	}
+	Success(c, gin.H{"discarded": n})
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Response 接口统一返回结构
+type Response struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data"`
+}
+
+// Success 返回成功结果
+func Success(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusOK, Response{Code: 0, Message: "success", Data: data})
+}
+
+// Fail 返回失败结果
+func Fail(c *gin.Context, httpCode int, message string) {
+	c.JSON(httpCode, Response{Code: httpCode, Message: message})
+}
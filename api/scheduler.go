@@ -0,0 +1,11 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-task-service/scheduler"
+)
+
+// LeaderStatus 查询当前调度器 leader 及任期
+func LeaderStatus(c *gin.Context) {
+	Success(c, scheduler.GetLeaderStatus())
+}
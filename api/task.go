@@ -0,0 +1,93 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go-task-service/core/curd_methods"
+	"go-task-service/scheduler"
+	"net/http"
+	"strconv"
+)
+
+// taskError 把调度器错误转换为对应的 HTTP 状态码
+func taskError(c *gin.Context, err error) {
+	if errors.Is(err, scheduler.ErrTaskNotFound) {
+		Fail(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, scheduler.ErrShuttingDown) || errors.Is(err, scheduler.ErrScheduleNotShared) {
+		Fail(c, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	Fail(c, http.StatusBadRequest, err.Error())
+}
+
+// ListTasks 查询所有定时任务及上次、下次执行时间
+func ListTasks(c *gin.Context) {
+	Success(c, scheduler.ListTasks())
+}
+
+// TriggerTask 立即执行一次任务
+func TriggerTask(c *gin.Context) {
+	if err := scheduler.TriggerTask(c.Param("name")); err != nil {
+		taskError(c, err)
+		return
+	}
+	Success(c, nil)
+}
+
+// PauseTask 暂停任务
+func PauseTask(c *gin.Context) {
+	if err := scheduler.PauseTask(c.Param("name")); err != nil {
+		taskError(c, err)
+		return
+	}
+	Success(c, nil)
+}
+
+// ResumeTask 恢复任务
+func ResumeTask(c *gin.Context) {
+	if err := scheduler.ResumeTask(c.Param("name")); err != nil {
+		taskError(c, err)
+		return
+	}
+	Success(c, nil)
+}
+
+type updateSpecReq struct {
+	Spec string `json:"spec" binding:"required"`
+}
+
+// UpdateTaskSpec 修改任务的 cron 表达式
+func UpdateTaskSpec(c *gin.Context) {
+	var req updateSpecReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Fail(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	if err := scheduler.UpdateTaskSpec(c.Param("name"), req.Spec); err != nil {
+		taskError(c, err)
+		return
+	}
+	Success(c, nil)
+}
+
+// ListTaskRuns 查询任务最近的执行记录
+func ListTaskRuns(c *gin.Context) {
+	name := c.Param("name")
+	if _, ok := scheduler.LookupTask(name); !ok {
+		Fail(c, http.StatusNotFound, scheduler.ErrTaskNotFound.Error())
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 500 {
+		Fail(c, http.StatusBadRequest, "limit 取值范围为 1-500")
+		return
+	}
+	records, err := curd_methods.QueryTaskRunRecords(name, limit)
+	if err != nil {
+		Fail(c, http.StatusInternalServerError, "查询执行记录失败: "+err.Error())
+		return
+	}
+	Success(c, records)
+}
@@ -0,0 +1,41 @@
+// aestool 生成和查看配置中的加密项，用于把 nacos 中的 {e} 配置逐步迁移到 {e2}。
+//
+//	go run ./cmd/aestool 'plain text'        # 输出 {e2} 密文
+//	go run ./cmd/aestool -d '{e}...'         # 解密 {e} 或 {e2} 密文
+//
+// 密钥环与服务相同，通过 APP_AES_KEYS 和 APP_AES_KEY_VERSION 环境变量指定，
+// 生成 {e2} 密文必须设置 APP_AES_KEYS，内置密钥只能解密 {e}
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-task-service/core/tools"
+	"log"
+)
+
+func main() {
+	decrypt := flag.Bool("d", false, "解密 {e} 或 {e2} 密文")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Fatal("用法: aestool [-d] <value>")
+	}
+	if err := tools.LoadAESKeysFromEnv(); err != nil {
+		log.Fatalf("加载AES密钥失败: %v", err)
+	}
+
+	value := flag.Arg(0)
+	var (
+		out string
+		err error
+	)
+	if *decrypt {
+		out, err = tools.DecryptConfigValue(value)
+	} else {
+		out, err = tools.EncryptAESGCM(value)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(out)
+}
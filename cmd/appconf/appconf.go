@@ -2,14 +2,20 @@ package appconf
 
 // nacos
 type Nacos struct {
-	Address string
-	Host    string
-	Port    int
-	User    string
-	Pass    string
-	DataId  string
-	Group   string
-	Key     string
+	Address    string
+	Host       string
+	Port       int
+	User       string
+	Pass       string
+	DataId     string
+	Group      string
+	Key        string
+	TaskDataId string // 定时任务调度配置的 dataId，为空时使用代码中的默认配置
+	// SnapshotPath 从 nacos 成功读取的配置保存到该文件，nacos 不可用时作为备用
+	SnapshotPath string
+	// LocalConfigPath 本地 JSON/YAML 配置文件，nacos 和快照都不可用时使用，可用 APP_CONFIG_FILE 环境变量覆盖。
+	// 文件不随代码提交，参考 cmd/appconfig.local.example.yaml 复制一份再修改
+	LocalConfigPath string
 }
 
 type AppConfig struct {
@@ -40,32 +46,67 @@ type ZapConf struct {
 	Path string `json:"path"`
 }
 
+// AppConfigMaster 应用配置，validate 标签在加载后统一校验，见 initialize.validateAppConfig
 type AppConfigMaster struct {
-	DBUSER                     string `json:"DB_USER"`
+	DBUSER                     string `json:"DB_USER" validate:"required"`
 	DBPASSWORD                 string `json:"DB_PASSWORD"`
-	DBNAME                     string `json:"DB_NAME"`
-	DBHOST                     string `json:"DB_HOST"`
-	DBPORT                     int    `json:"DB_PORT"`
+	DBNAME                     string `json:"DB_NAME" validate:"required"`
+	DBHOST                     string `json:"DB_HOST" validate:"required"`
+	DBPORT                     int    `json:"DB_PORT" validate:"required,min=1,max=65535"`
 	REDISPASSWORD              string `json:"REDIS_PASSWORD"`
-	REDISHOST                  string `json:"REDIS_HOST"`
-	REDISPORT                  int    `json:"REDIS_PORT"`
-	REDISDB                    int    `json:"REDIS_DB"`
+	REDISHOST                  string `json:"REDIS_HOST" validate:"required"`
+	REDISPORT                  int    `json:"REDIS_PORT" validate:"required,min=1,max=65535"`
+	REDISDB                    int    `json:"REDIS_DB" validate:"min=0,max=15"`
 	REDISCLUSTERHOST           string `json:"REDIS_CLUSTER_HOST"`
-	REDISCLUSTERPORT           int    `json:"REDIS_CLUSTER_PORT"`
-	REDISCLUSTERDB             int    `json:"REDIS_CLUSTER_DB"`
+	REDISCLUSTERPORT           int    `json:"REDIS_CLUSTER_PORT" validate:"omitempty,min=1,max=65535"`
+	REDISCLUSTERDB             int    `json:"REDIS_CLUSTER_DB" validate:"min=0,max=15"`
 	REDISCLUSTERPASSWORD       string `json:"REDIS_CLUSTER_PASSWORD"`
 	AESCRYPTKEY                string `json:"AES_CRYPT_KEY"`
 	SECRETKEY                  string `json:"SECRET_KEY"`
-	ESNODE                     string `json:"ES_NODE"`
-	ESAPIKEY                   string `json:"ES_APIKEY"`
-	YYMAPIHOST                 string `json:"YYM_API_HOST"`
+	ESNODE                     string `json:"ES_NODE" validate:"omitempty,url"` // 单个节点，已被 ES_ADDRESSES 取代
+	ESAPIKEY                   string `json:"ES_APIKEY" validate:"excluded_with=ESUSERNAME"`
+	YYMAPIHOST                 string `json:"YYM_API_HOST" validate:"omitempty,url"`
 	YYMSUPERPROXY              string `json:"YYM_SUPER_PROXY"`
 	CHECKOUTCASHTOKEN          string `json:"CHECKOUT_CASH_TOKEN"`
 	C5GAMECLIENTID             string `json:"C5GAME_CLIENT_ID"`
-	C5GAMEREDIRECTURI          string `json:"C5GAME_REDIRECT_URI"`
+	C5GAMEREDIRECTURI          string `json:"C5GAME_REDIRECT_URI" validate:"omitempty,url"`
 	ALIPAYAPPCERTPATH          string `json:"ALIPAY_APP_CERT_PATH"`
 	ALIPAYALIPAYROOTCERTPATH   string `json:"ALIPAY_ALIPAY_ROOT_CERT_PATH"`
 	ALIPAYALIPAYPUBLICCERTPATH string `json:"ALIPAY_ALIPAY_PUBLIC_CERT_PATH"`
-	YYMWEBHOST                 string `json:"YYM_WEB_HOST"`
+	YYMWEBHOST                 string `json:"YYM_WEB_HOST" validate:"omitempty,url"`
 	ZAP_LOG_PATH               string `json:"ZAP_LOG_PATH"`
+	ADMINTOKEN                 string `json:"ADMIN_TOKEN"`
+	SCHEDULERLEADERELECTION    bool   `json:"SCHEDULER_LEADER_ELECTION"`
+	SCHEDULERLEADERTTL         int    `json:"SCHEDULER_LEADER_TTL" validate:"omitempty,min=3"`
+	SCHEDULERTASKTIMEOUT       int    `json:"SCHEDULER_TASK_TIMEOUT" validate:"min=0"`
+	SCHEDULERREQUIRESHARED     bool   `json:"SCHEDULER_REQUIRE_SHARED"` // 为 true 时未配置 nacos 任务调度 dataId 则拒绝修改调度，否则只在本实例生效
+	SHUTDOWNTIMEOUT            int    `json:"SHUTDOWN_TIMEOUT" validate:"min=0"`
+	INVENTORYPAGESIZE          int    `json:"INVENTORY_PAGE_SIZE" validate:"omitempty,min=1,max=10000"`
+	INVENTORYREFRESHTTL        int    `json:"INVENTORY_REFRESH_TTL" validate:"min=0"`
+	// INVENTORYUNAVAILABLESTATUS 对账发现物品已不在 steam 账号中时写入的 sell_status，需与 go-db-model 中的取值一致，为 0 时只记录差异不修改状态
+	INVENTORYUNAVAILABLESTATUS int    `json:"INVENTORY_UNAVAILABLE_STATUS" validate:"min=0"`
+	SNOWFLAKENODEID            int64  `json:"SNOWFLAKE_NODE_ID" validate:"min=0,max=1023"`
+	PUBLISHCONCURRENCY         int    `json:"PUBLISH_CONCURRENCY" validate:"min=0"`
+	PUBLISHMAXATTEMPTS         int    `json:"PUBLISH_MAX_ATTEMPTS" validate:"min=0"`
+	// rocketmq，生产者和消费者组必须分别配置且不能相同
+	ROCKETMQNAMESERVERS          []string `json:"ROCKETMQ_NAME_SERVERS" validate:"required,min=1,dive,hostname_port"`
+	ROCKETMQPRODUCERGROUP        string   `json:"ROCKETMQ_PRODUCER_GROUP" validate:"required"`
+	ROCKETMQCONSUMERGROUP        string   `json:"ROCKETMQ_CONSUMER_GROUP" validate:"required,nefield=ROCKETMQPRODUCERGROUP"`
+	ROCKETMQACCESSKEY            string   `json:"ROCKETMQ_ACCESS_KEY" validate:"required_with=ROCKETMQSECRETKEY"`
+	ROCKETMQSECRETKEY            string   `json:"ROCKETMQ_SECRET_KEY" validate:"required_with=ROCKETMQACCESSKEY"`
+	ROCKETMQNAMESPACE            string   `json:"ROCKETMQ_NAMESPACE"`
+	ROCKETMQPRODUCERRETRIES      int      `json:"ROCKETMQ_PRODUCER_RETRIES" validate:"min=0"`
+	ROCKETMQSENDTIMEOUT          int      `json:"ROCKETMQ_SEND_TIMEOUT" validate:"min=0"` // 毫秒
+	ROCKETMQCONSUMERRETRIES      int      `json:"ROCKETMQ_CONSUMER_RETRIES" validate:"min=0"`
+	ROCKETMQCONSUMERMAXRECONSUME int32    `json:"ROCKETMQ_CONSUMER_MAX_RECONSUME" validate:"min=0"`
+	// mongodb
+	MONGOURI       string `json:"MONGO_URI" validate:"required,url"`
+	MONGODATABASE  string `json:"MONGO_DATABASE" validate:"required"`
+	MONGOTLSCAFILE string `json:"MONGO_TLS_CA_FILE" validate:"omitempty,file"`
+	// elasticsearch，基础认证和 API key 二选一
+	ESADDRESSES   []string `json:"ES_ADDRESSES" validate:"required_without=ESNODE,dive,url"`
+	ESUSERNAME    string   `json:"ES_USERNAME" validate:"required_with=ESPASSWORD"`
+	ESPASSWORD    string   `json:"ES_PASSWORD" validate:"required_with=ESUSERNAME"`
+	ESCACERTFILE  string   `json:"ES_CA_CERT_FILE" validate:"omitempty,file"`
+	ESFINGERPRINT string   `json:"ES_CA_FINGERPRINT" validate:"omitempty,hexadecimal"` // CA 证书的 SHA256 指纹
 }
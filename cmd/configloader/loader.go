@@ -0,0 +1,239 @@
+package configloader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-task-service/cmd/appconf"
+	"go-task-service/core/tools"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 应用配置来源，按优先级从高到低依次尝试
+const (
+	SourceNacos    = "nacos"    // nacos 配置中心
+	SourceSnapshot = "snapshot" // 上一次从 nacos 成功读取的配置快照
+	SourceLocal    = "local"    // 本地 JSON/YAML 配置文件
+)
+
+// LocalConfigEnv 环境变量指定本地配置文件，优先于 appconfig.yaml 中的 localConfigPath
+const LocalConfigEnv = "APP_CONFIG_FILE"
+
+// Options 配置加载参数，与 initialize 中的 nacos 客户端解耦，便于单独测试
+type Options struct {
+	// FetchNacos 读取 nacos 中的原始配置，为 nil 时跳过 nacos
+	FetchNacos   func() (string, error)
+	SnapshotPath string
+	LocalPath    string
+}
+
+// Result 加载结果，Content 为解密前的原始内容，用于写入快照
+type Result struct {
+	Conf    *appconf.AppConfigMaster
+	Source  string
+	Content string
+}
+
+// Load 依次从 nacos、磁盘快照、本地文件读取配置，全部失败时返回合并后的错误
+func Load(opts Options) (*Result, error) {
+	var errs []error
+
+	if opts.FetchNacos != nil {
+		content, err := opts.FetchNacos()
+		if err == nil {
+			conf, err := Parse(content)
+			if err == nil {
+				return &Result{Conf: conf, Source: SourceNacos, Content: content}, nil
+			}
+			errs = append(errs, fmt.Errorf("nacos: %w", err))
+		} else {
+			errs = append(errs, fmt.Errorf("nacos: %w", err))
+		}
+	}
+
+	if path := opts.SnapshotPath; path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			conf, err := Parse(string(data))
+			if err == nil {
+				return &Result{Conf: conf, Source: SourceSnapshot + ":" + path, Content: string(data)}, nil
+			}
+			errs = append(errs, fmt.Errorf("快照 %s: %w", path, err))
+		} else {
+			errs = append(errs, fmt.Errorf("快照 %s: %w", path, err))
+		}
+	}
+
+	path := os.Getenv(LocalConfigEnv)
+	if path == "" {
+		path = opts.LocalPath
+	}
+	if path != "" {
+		content, err := ReadLocal(path)
+		if err == nil {
+			conf, err := Parse(content)
+			if err == nil {
+				return &Result{Conf: conf, Source: SourceLocal + ":" + path, Content: content}, nil
+			}
+			errs = append(errs, fmt.Errorf("本地配置 %s: %w", path, err))
+		} else {
+			errs = append(errs, fmt.Errorf("本地配置 %s: %w", path, err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("未配置任何配置来源")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Parse 解析 JSON 配置，解密 {e} 开头的加密项
+func Parse(content string) (*appconf.AppConfigMaster, error) {
+	var configs map[string]interface{}
+	err := json.Unmarshal([]byte(content), &configs)
+	if err != nil {
+		return nil, fmt.Errorf("反序列化nacos config 到 AppConfig 失败: %w", err)
+	}
+	for k, v := range configs {
+		//字符串类型的配置项和字符串列表中的元素如果是加密数据（{e} 或 {e2}）则解密
+		switch val := v.(type) {
+		case string:
+			plain, err := tools.DecryptConfigValue(val)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			configs[k] = plain
+		case []interface{}:
+			for i, item := range val {
+				str, ok := item.(string)
+				if !ok {
+					continue
+				}
+				plain, err := tools.DecryptConfigValue(str)
+				if err != nil {
+					return nil, fmt.Errorf("%s[%d]: %w", k, i, err)
+				}
+				val[i] = plain
+			}
+		}
+	}
+	// 将解密后的配置重新序列化并解析到 AppConfigMaster
+	configBytes, err := json.Marshal(configs)
+	if err != nil {
+		return nil, fmt.Errorf("重新序列化解密后的配置失败: %w", err)
+	}
+	conf := &appconf.AppConfigMaster{}
+	if err := json.Unmarshal(configBytes, conf); err != nil {
+		return nil, fmt.Errorf("解析配置到 AppConfigMaster 失败: %w", err)
+	}
+	return conf, nil
+}
+
+// SaveSnapshot 把 nacos 原始配置写入磁盘作为快照，加密项保持加密状态。
+// 先写临时文件再重命名，避免写到一半时进程退出留下损坏的快照
+func SaveSnapshot(path, content string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("创建配置快照目录失败: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0o600); err != nil {
+		return fmt.Errorf("写入配置快照失败: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("替换配置快照失败: %w", err)
+	}
+	return nil
+}
+
+// ReadLocal 读取本地配置文件，.yaml/.yml 转换为 JSON，其他按 JSON 处理
+func ReadLocal(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var configs map[string]interface{}
+		if err := yaml.Unmarshal(data, &configs); err != nil {
+			return "", fmt.Errorf("解析YAML失败: %w", err)
+		}
+		data, err = json.Marshal(configs)
+		if err != nil {
+			return "", err
+		}
+	}
+	return string(data), nil
+}
+
+// ApplyEnvOverrides 用与 JSON 字段同名的环境变量覆盖配置（如 DB_PASSWORD），
+// 支持 {e}/{e2} 加密值，列表字段用逗号分隔且每一项可以单独加密，返回被覆盖的字段名
+func ApplyEnvOverrides(conf *appconf.AppConfigMaster) ([]string, error) {
+	var applied []string
+	v := reflect.ValueOf(conf).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		value, err := tools.DecryptConfigValue(value)
+		if err != nil {
+			return applied, fmt.Errorf("环境变量 %s: %w", name, err)
+		}
+		if err := setField(v.Field(i), value); err != nil {
+			return applied, fmt.Errorf("环境变量 %s: %w", name, err)
+		}
+		applied = append(applied, name)
+	}
+	return applied, nil
+}
+
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("不支持的字段类型 %s", field.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			// 列表中的每一项都可以单独加密
+			item, err := tools.DecryptConfigValue(item)
+			if err != nil {
+				return err
+			}
+			items = append(items, item)
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("不支持的字段类型 %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package configloader
+
+import (
+	"encoding/json"
+	"errors"
+	"go-task-service/cmd/appconf"
+	"go-task-service/core/tools"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyEnvOverrides(t *testing.T) {
+	t.Setenv("DB_HOST", "db.internal")
+	t.Setenv("DB_PORT", "3307")
+	t.Setenv("SCHEDULER_LEADER_ELECTION", "true")
+	t.Setenv("ROCKETMQ_NAME_SERVERS", " mq-a:9876, {e}"+tools.EncryptAES("mq-b:9876", "base64")+",, ")
+	t.Setenv("DB_PASSWORD", "{e}"+tools.EncryptAES("secret", "base64"))
+	// 未设置的环境变量不覆盖原值
+	t.Setenv("DB_USER", "")
+	os.Unsetenv("DB_USER")
+
+	conf := &appconf.AppConfigMaster{DBUSER: "root", DBHOST: "localhost", DBPORT: 3306}
+	applied, err := ApplyEnvOverrides(conf)
+	if err != nil {
+		t.Fatalf("ApplyEnvOverrides() error = %v", err)
+	}
+	if conf.DBHOST != "db.internal" || conf.DBPORT != 3307 || !conf.SCHEDULERLEADERELECTION {
+		t.Errorf("scalar overrides = %q %d %v", conf.DBHOST, conf.DBPORT, conf.SCHEDULERLEADERELECTION)
+	}
+	if conf.DBPASSWORD != "secret" {
+		t.Errorf("DB_PASSWORD = %q, want decrypted value", conf.DBPASSWORD)
+	}
+	if want := []string{"mq-a:9876", "mq-b:9876"}; !reflect.DeepEqual(conf.ROCKETMQNAMESERVERS, want) {
+		t.Errorf("ROCKETMQ_NAME_SERVERS = %q, want %q", conf.ROCKETMQNAMESERVERS, want)
+	}
+	if conf.DBUSER != "root" {
+		t.Errorf("DB_USER = %q, unset env must not override", conf.DBUSER)
+	}
+	for _, name := range []string{"DB_HOST", "DB_PORT", "DB_PASSWORD", "SCHEDULER_LEADER_ELECTION", "ROCKETMQ_NAME_SERVERS"} {
+		if !contains(applied, name) {
+			t.Errorf("applied = %v, missing %s", applied, name)
+		}
+	}
+	if contains(applied, "DB_USER") {
+		t.Errorf("applied = %v, must not contain DB_USER", applied)
+	}
+}
+
+func TestApplyEnvOverridesInvalid(t *testing.T) {
+	tests := []struct {
+		name, env, value string
+	}{
+		{"int", "DB_PORT", "abc"},
+		{"bool", "SCHEDULER_LEADER_ELECTION", "maybe"},
+		{"encrypted", "DB_PASSWORD", "{e2}not-base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.env, tt.value)
+			_, err := ApplyEnvOverrides(&appconf.AppConfigMaster{})
+			if err == nil || !strings.Contains(err.Error(), tt.env) {
+				t.Errorf("ApplyEnvOverrides() error = %v, want error naming %s", err, tt.env)
+			}
+		})
+	}
+}
+
+func TestLoadFallbackOrder(t *testing.T) {
+	t.Setenv(LocalConfigEnv, "")
+
+	dir := t.TempDir()
+	snapshot := filepath.Join(dir, "snapshot.json")
+	writeFile(t, snapshot, `{"DB_NAME":"from-snapshot"}`)
+	local := filepath.Join(dir, "local.yaml")
+	writeFile(t, local, "DB_NAME: from-local\nROCKETMQ_NAME_SERVERS:\n  - mq:9876\n")
+	broken := filepath.Join(dir, "broken.json")
+	writeFile(t, broken, `{"DB_NAME":`)
+	missing := filepath.Join(dir, "missing.json")
+
+	nacosOK := func() (string, error) { return `{"DB_NAME":"from-nacos"}`, nil }
+	nacosDown := func() (string, error) { return "", errors.New("connection refused") }
+	nacosBroken := func() (string, error) { return "not json", nil }
+
+	tests := []struct {
+		name       string
+		opts       Options
+		wantSource string
+		wantDBName string
+	}{
+		{"nacos first", Options{FetchNacos: nacosOK, SnapshotPath: snapshot, LocalPath: local}, SourceNacos, "from-nacos"},
+		{"nacos down uses snapshot", Options{FetchNacos: nacosDown, SnapshotPath: snapshot, LocalPath: local}, SourceSnapshot + ":" + snapshot, "from-snapshot"},
+		{"unparsable nacos uses snapshot", Options{FetchNacos: nacosBroken, SnapshotPath: snapshot, LocalPath: local}, SourceSnapshot + ":" + snapshot, "from-snapshot"},
+		{"no nacos client uses snapshot", Options{SnapshotPath: snapshot, LocalPath: local}, SourceSnapshot + ":" + snapshot, "from-snapshot"},
+		{"missing snapshot uses local", Options{FetchNacos: nacosDown, SnapshotPath: missing, LocalPath: local}, SourceLocal + ":" + local, "from-local"},
+		{"broken snapshot uses local", Options{FetchNacos: nacosDown, SnapshotPath: broken, LocalPath: local}, SourceLocal + ":" + local, "from-local"},
+		{"no snapshot path uses local", Options{FetchNacos: nacosDown, LocalPath: local}, SourceLocal + ":" + local, "from-local"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Load(tt.opts)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if res.Source != tt.wantSource || res.Conf.DBNAME != tt.wantDBName {
+				t.Errorf("Load() = %s/%s, want %s/%s", res.Source, res.Conf.DBNAME, tt.wantSource, tt.wantDBName)
+			}
+		})
+	}
+}
+
+func TestLoadLocalFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	fromEnv := filepath.Join(dir, "env.json")
+	writeFile(t, fromEnv, `{"DB_NAME":"from-env-file"}`)
+	t.Setenv(LocalConfigEnv, fromEnv)
+
+	res, err := Load(Options{LocalPath: filepath.Join(dir, "missing.yaml")})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if res.Source != SourceLocal+":"+fromEnv || res.Conf.DBNAME != "from-env-file" {
+		t.Errorf("Load() = %s/%s, want the file from %s", res.Source, res.Conf.DBNAME, LocalConfigEnv)
+	}
+}
+
+func TestLoadAllSourcesFail(t *testing.T) {
+	t.Setenv(LocalConfigEnv, "")
+	dir := t.TempDir()
+	_, err := Load(Options{
+		FetchNacos:   func() (string, error) { return "", errors.New("connection refused") },
+		SnapshotPath: filepath.Join(dir, "snapshot.json"),
+		LocalPath:    filepath.Join(dir, "local.yaml"),
+	})
+	if err == nil {
+		t.Fatal("Load() error = nil, want error")
+	}
+	for _, part := range []string{"nacos", "快照", "本地配置"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("Load() error = %v, missing %q", err, part)
+		}
+	}
+
+	if _, err := Load(Options{}); err == nil {
+		t.Error("Load(Options{}) error = nil, want error")
+	}
+}
+
+func TestSaveSnapshotRoundTrip(t *testing.T) {
+	t.Setenv(LocalConfigEnv, "")
+	path := filepath.Join(t.TempDir(), "nested", "snapshot.json")
+	content := `{"DB_NAME":"saved"}`
+	if err := SaveSnapshot(path, content); err != nil {
+		t.Fatalf("SaveSnapshot() error = %v", err)
+	}
+	res, err := Load(Options{SnapshotPath: path})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if res.Content != content || res.Conf.DBNAME != "saved" {
+		t.Errorf("Load() = %q, want saved snapshot", res.Content)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// 示例配置需要覆盖 AppConfigMaster 的全部字段，新增配置项时同步更新
+func TestExampleConfigCoversAllFields(t *testing.T) {
+	content, err := ReadLocal("../appconfig.local.example.yaml")
+	if err != nil {
+		t.Fatalf("ReadLocal() error = %v", err)
+	}
+	var keys map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &keys); err != nil {
+		t.Fatal(err)
+	}
+	typ := reflect.TypeOf(appconf.AppConfigMaster{})
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		if _, ok := keys[name]; !ok {
+			t.Errorf("appconfig.local.example.yaml 缺少 %s", name)
+		}
+	}
+	if _, err := Parse(content); err != nil {
+		t.Errorf("Parse() error = %v", err)
+	}
+}
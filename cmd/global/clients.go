@@ -0,0 +1,152 @@
+package global
+
+import (
+	"github.com/apache/rocketmq-client-go/v2"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-redis/redis"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 配置热更新时会被替换的客户端，读取方通过同名函数获取当前生效的实例，
+// 与 Config() 一样，同一段逻辑应先取出再使用
+var (
+	db       atomic.Pointer[gorm.DB]
+	redisDB  atomic.Pointer[redis.Client]
+	esClient atomic.Pointer[elasticsearch.Client]
+	mongoDB  atomic.Pointer[mongo.Database]
+	// rocketmq 的客户端是接口类型，包一层结构体再放入 atomic.Pointer
+	rocketMQProducer atomic.Pointer[rocketMQProducerHolder]
+	rocketMQConsumer atomic.Pointer[rocketMQConsumerHolder]
+)
+
+type rocketMQProducerHolder struct{ producer rocketmq.Producer }
+
+type rocketMQConsumerHolder struct{ consumer rocketmq.PushConsumer }
+
+// DB 返回当前的 MySQL 连接
+func DB() *gorm.DB { return db.Load() }
+
+// RedisDB 返回当前的 Redis 客户端
+func RedisDB() *redis.Client { return redisDB.Load() }
+
+// ESClient 返回当前的 Elasticsearch 客户端
+func ESClient() *elasticsearch.Client { return esClient.Load() }
+
+// MongoDB 返回当前的 MongoDB 数据库
+func MongoDB() *mongo.Database { return mongoDB.Load() }
+
+// RocketMQProducer 返回当前的 rocketmq 生产者
+func RocketMQProducer() rocketmq.Producer {
+	if h := rocketMQProducer.Load(); h != nil {
+		return h.producer
+	}
+	return nil
+}
+
+// RocketMQConsumer 返回当前的 rocketmq 消费者
+func RocketMQConsumer() rocketmq.PushConsumer {
+	if h := rocketMQConsumer.Load(); h != nil {
+		return h.consumer
+	}
+	return nil
+}
+
+// SetDB 替换 MySQL 连接，返回被替换的旧连接
+func SetDB(v *gorm.DB) *gorm.DB { return db.Swap(v) }
+
+// SetRedisDB 替换 Redis 客户端，返回被替换的旧客户端
+func SetRedisDB(v *redis.Client) *redis.Client { return redisDB.Swap(v) }
+
+// SetESClient 替换 Elasticsearch 客户端，返回被替换的旧客户端
+func SetESClient(v *elasticsearch.Client) *elasticsearch.Client { return esClient.Swap(v) }
+
+// SetMongoDB 替换 MongoDB 数据库，返回被替换的旧实例
+func SetMongoDB(v *mongo.Database) *mongo.Database { return mongoDB.Swap(v) }
+
+// SetRocketMQProducer 替换 rocketmq 生产者，返回被替换的旧生产者
+func SetRocketMQProducer(v rocketmq.Producer) rocketmq.Producer {
+	if old := rocketMQProducer.Swap(&rocketMQProducerHolder{producer: v}); old != nil {
+		return old.producer
+	}
+	return nil
+}
+
+// SetRocketMQConsumer 替换 rocketmq 消费者，返回被替换的旧消费者
+func SetRocketMQConsumer(v rocketmq.PushConsumer) rocketmq.PushConsumer {
+	if old := rocketMQConsumer.Swap(&rocketMQConsumerHolder{consumer: v}); old != nil {
+		return old.consumer
+	}
+	return nil
+}
+
+// 旧客户端等待使用方结束的最长时间，超过后仍然关闭
+const clientDrainTimeout = time.Hour
+
+// clientUsage 按代记录正在使用客户端的工作单元。每次替换客户端进入新的一代，
+// 旧客户端等上一代及更早开始的工作单元全部结束后再关闭
+var clientUsage = struct {
+	mu     sync.Mutex
+	gen    uint64
+	active map[uint64]int
+}{active: make(map[uint64]int)}
+
+// UseClients 登记一个使用全局客户端的工作单元（一次任务执行、一条消息、一次 HTTP 请求、
+// 一批发件箱投递等），结束时调用返回的函数。工作单元可能在开始时取到旧客户端并一直使用到结束
+func UseClients() (done func()) {
+	clientUsage.mu.Lock()
+	gen := clientUsage.gen
+	clientUsage.active[gen]++
+	clientUsage.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			clientUsage.mu.Lock()
+			defer clientUsage.mu.Unlock()
+			if clientUsage.active[gen]--; clientUsage.active[gen] == 0 {
+				delete(clientUsage.active, gen)
+			}
+		})
+	}
+}
+
+// RetireClient 在新客户端替换旧客户端之后调用，等替换前开始的工作单元全部结束再执行 closeFn
+func RetireClient(name string, closeFn func() error) {
+	clientUsage.mu.Lock()
+	retiredGen := clientUsage.gen
+	clientUsage.gen++
+	clientUsage.mu.Unlock()
+
+	go func() {
+		deadline := time.Now().Add(clientDrainTimeout)
+		for activeSince(retiredGen) > 0 {
+			if time.Now().After(deadline) {
+				zap.L().Warn("等待旧连接的使用方结束超时，直接关闭", zap.String("client", name), zap.Int("active", activeSince(retiredGen)))
+				break
+			}
+			time.Sleep(time.Second)
+		}
+		if err := closeFn(); err != nil {
+			zap.L().Error("关闭旧连接失败", zap.String("client", name), zap.Error(err))
+			return
+		}
+		zap.L().Info("旧连接已关闭", zap.String("client", name))
+	}()
+}
+
+// activeSince 返回 gen 及更早代中仍在进行的工作单元数
+func activeSince(gen uint64) int {
+	clientUsage.mu.Lock()
+	defer clientUsage.mu.Unlock()
+	n := 0
+	for g, count := range clientUsage.active {
+		if g <= gen {
+			n += count
+		}
+	}
+	return n
+}
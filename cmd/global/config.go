@@ -0,0 +1,23 @@
+package global
+
+import (
+	"go-task-service/cmd/appconf"
+	"sync/atomic"
+)
+
+// appConfigMaster 当前生效的应用配置，热更新时整体替换
+var appConfigMaster atomic.Pointer[appconf.AppConfigMaster]
+
+// Config 返回当前生效的应用配置。返回值只读，热更新会替换为新的对象而不是修改原对象，
+// 同一段逻辑需要读取多个字段时应先取出再使用，保证读到的是同一版本
+func Config() *appconf.AppConfigMaster {
+	if conf := appConfigMaster.Load(); conf != nil {
+		return conf
+	}
+	return &appconf.AppConfigMaster{}
+}
+
+// SetConfig 替换当前生效的应用配置
+func SetConfig(conf *appconf.AppConfigMaster) {
+	appConfigMaster.Store(conf)
+}
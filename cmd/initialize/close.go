@@ -0,0 +1,53 @@
+package initialize
+
+import (
+	"context"
+	"fmt"
+	"go-task-service/cmd/global"
+	"go.uber.org/zap"
+)
+
+// Close 按顺序关闭 rocketmq 生产者、消费者、MongoDB、Redis 和 MySQL 连接
+func Close(ctx context.Context) {
+	if producer := global.RocketMQProducer(); producer != nil {
+		if err := producer.Shutdown(); err != nil {
+			zap.L().Error("关闭rocketmq生产者失败", zap.Error(err))
+		} else {
+			zap.L().Info("rocketmq生产者已关闭")
+		}
+	}
+	if consumer := global.RocketMQConsumer(); consumer != nil {
+		if err := consumer.Shutdown(); err != nil {
+			zap.L().Error("关闭rocketmq消费者失败", zap.Error(err))
+		} else {
+			zap.L().Info("rocketmq消费者已关闭")
+		}
+	}
+	if mongoDB := global.MongoDB(); mongoDB != nil {
+		if err := mongoDB.Client().Disconnect(ctx); err != nil {
+			zap.L().Error("关闭MongoDB连接失败", zap.Error(err))
+		} else {
+			zap.L().Info("MongoDB连接已关闭")
+		}
+	}
+	if redisDB := global.RedisDB(); redisDB != nil {
+		if err := redisDB.Close(); err != nil {
+			zap.L().Error("关闭Redis连接失败", zap.Error(err))
+		} else {
+			zap.L().Info("Redis连接已关闭")
+		}
+	}
+	if db := global.DB(); db != nil {
+		if sqlDB, err := db.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				zap.L().Error("关闭MySQL连接池失败", zap.Error(err))
+			} else {
+				zap.L().Info("MySQL连接池已关闭")
+			}
+		}
+	}
+	if global.ZapLog != nil {
+		_ = global.ZapLog.Sync()
+	}
+	fmt.Println("所有客户端连接已关闭")
+}
@@ -0,0 +1,86 @@
+package initialize
+
+import (
+	"errors"
+	"fmt"
+	"go-task-service/cmd/configloader"
+	"go-task-service/cmd/global"
+	"log"
+	"strings"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients"
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+)
+
+// ConfigSource 启动时实际使用的配置来源
+var ConfigSource string
+
+// loadAppConfig 依次从 nacos、磁盘快照、本地文件读取配置，解析和回退逻辑见 configloader.Load
+func loadAppConfig() (*configloader.Result, error) {
+	return configloader.Load(configloader.Options{
+		FetchNacos:   fetchNacosConfig,
+		SnapshotPath: global.Nacos.SnapshotPath,
+		LocalPath:    global.Nacos.LocalConfigPath,
+	})
+}
+
+// newNacosClient 按 appconfig.yaml 中的 nacos 信息创建配置客户端
+func newNacosClient() (config_client.IConfigClient, error) {
+	if global.Nacos.Host == "" {
+		return nil, errors.New("未配置nacos地址")
+	}
+	// 创建clientConfig
+	clientConfig := constant.ClientConfig{
+		NamespaceId:         global.Nacos.Address, // 如果需要支持多namespace，我们可以创建多个client,它们有不同的NamespaceId。当namespace是public时，此处填空字符串。
+		TimeoutMs:           5000,
+		NotLoadCacheAtStart: true,
+		LogDir:              "/tmp/nacos/log",
+		CacheDir:            "/tmp/nacos/cache",
+		LogLevel:            "debug",
+		Username:            global.Nacos.User,
+		Password:            global.Nacos.Pass,
+	}
+	// 至少一个ServerConfig
+	serverConfigs := []constant.ServerConfig{
+		{
+			IpAddr: global.Nacos.Host,
+			Port:   uint64(global.Nacos.Port),
+		},
+	}
+	// 创建动态配置客户端
+	return clients.CreateConfigClient(map[string]interface{}{
+		"serverConfigs": serverConfigs,
+		"clientConfig":  clientConfig,
+	})
+}
+
+// fetchNacosConfig 创建 nacos 客户端并读取应用配置，客户端创建成功即保存到 global.NacosClient，
+// 即使本次读取失败，之后仍可通过监听收到配置
+func fetchNacosConfig() (string, error) {
+	client, err := newNacosClient()
+	if err != nil {
+		return "", fmt.Errorf("创建nacos客户端失败: %w", err)
+	}
+	global.NacosClient = client
+	//通过nacos客户端去获取config
+	content, err := client.GetConfig(vo.ConfigParam{
+		DataId: global.Nacos.DataId,
+		Group:  global.Nacos.Group,
+	})
+	if err != nil {
+		return "", fmt.Errorf("获取nacos config 失败: %w", err)
+	}
+	if strings.TrimSpace(content) == "" {
+		return "", errors.New("nacos config 为空")
+	}
+	return content, nil
+}
+
+// saveConfigSnapshot 把 nacos 原始配置写入 appconfig.yaml 中 snapshotPath 指定的快照，失败只记录日志
+func saveConfigSnapshot(content string) {
+	if err := configloader.SaveSnapshot(global.Nacos.SnapshotPath, content); err != nil {
+		log.Println(err)
+	}
+}
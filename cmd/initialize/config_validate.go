@@ -0,0 +1,92 @@
+package initialize
+
+import (
+	"errors"
+	"fmt"
+	"go-task-service/cmd/appconf"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var configValidate = newConfigValidator()
+
+// newConfigValidator 创建校验器，错误信息中的字段名使用 JSON 字段名，与 nacos 中的 key 一致
+func newConfigValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(jsonFieldName)
+	return v
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// validateAppConfig 按 AppConfigMaster 上的 validate 标签校验配置，一次返回全部问题
+func validateAppConfig(conf *appconf.AppConfigMaster) error {
+	err := configValidate.Struct(conf)
+	if err == nil {
+		return nil
+	}
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+	problems := make([]string, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		problems = append(problems, fe.Field()+": "+describeConfigError(fe))
+	}
+	return fmt.Errorf("配置校验失败，共 %d 处问题:\n  %s", len(problems), strings.Join(problems, "\n  "))
+}
+
+// describeConfigError 把校验失败的标签转换为可读的说明。
+// 只说明字段和规则，不带配置值，URL 中可能包含账号密码，错误会直接 panic 到启动日志
+func describeConfigError(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "不能为空"
+	case "min":
+		if fe.Kind() == reflect.Slice {
+			return "至少需要 " + fe.Param() + " 项"
+		}
+		return "不能小于 " + fe.Param()
+	case "max":
+		return "不能大于 " + fe.Param()
+	case "url":
+		return "不是合法的 URL"
+	case "hostname_port":
+		return "需为 host:port 格式"
+	case "oneof":
+		return "取值必须为以下之一: " + fe.Param()
+	case "nefield":
+		return "不能与 " + configFieldJSONName(fe.Param()) + " 相同"
+	case "required_with":
+		return "需与 " + configFieldJSONName(fe.Param()) + " 同时配置"
+	case "required_without":
+		return "与 " + configFieldJSONName(fe.Param()) + " 至少配置一个"
+	case "excluded_with":
+		return "不能与 " + configFieldJSONName(fe.Param()) + " 同时配置"
+	case "file":
+		return "文件不存在"
+	case "hexadecimal":
+		return "需为十六进制字符串"
+	default:
+		return fmt.Sprintf("不满足校验规则 %s", fe.Tag())
+	}
+}
+
+// configFieldJSONName 把标签参数中的结构体字段名转换为 JSON 字段名
+func configFieldJSONName(name string) string {
+	if field, ok := reflect.TypeOf(appconf.AppConfigMaster{}).FieldByName(name); ok {
+		return jsonFieldName(field)
+	}
+	return name
+}
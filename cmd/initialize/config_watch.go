@@ -0,0 +1,162 @@
+package initialize
+
+import (
+	"encoding/json"
+	"errors"
+	"go-task-service/cmd/appconf"
+	"go-task-service/cmd/configloader"
+	"go-task-service/cmd/global"
+	"go.uber.org/zap"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+)
+
+// 配置分组，按 JSON 字段名前缀划分，对应字段变化时回调该分组注册的函数
+const (
+	SectionMySQL         = "DB_"
+	SectionRedis         = "REDIS_"
+	SectionElasticsearch = "ES_"
+	SectionRocketMQ      = "ROCKETMQ_"
+	SectionMongoDB       = "MONGO_"
+	// SectionAll 任意字段变化都会回调
+	SectionAll = ""
+)
+
+// ConfigChangeFunc 配置变更回调，oldConf 和 newConf 都是只读的完整配置
+type ConfigChangeFunc func(oldConf, newConf *appconf.AppConfigMaster) error
+
+type configCallback struct {
+	section string
+	name    string
+	fn      ConfigChangeFunc
+}
+
+var (
+	callbacksMu     sync.Mutex
+	configCallbacks []configCallback
+	// reloadMu 保证配置变更按顺序逐个处理
+	reloadMu sync.Mutex
+)
+
+// OnConfigChange 注册配置变更回调，section 为 Section* 常量，name 用于日志。
+// 回调在新配置生效之后按注册顺序执行，返回错误只记录日志，不会回滚配置
+func OnConfigChange(section, name string, fn ConfigChangeFunc) {
+	callbacksMu.Lock()
+	defer callbacksMu.Unlock()
+	configCallbacks = append(configCallbacks, configCallback{section: section, name: name, fn: fn})
+}
+
+// WatchAppConfig 监听 nacos 中的应用配置，变更时重新解析、解密并替换 global.Config()
+func WatchAppConfig() error {
+	if global.NacosClient == nil || global.Nacos == nil {
+		return errors.New("nacos客户端未初始化")
+	}
+	registerReconnectors()
+	err := global.NacosClient.ListenConfig(vo.ConfigParam{
+		DataId: global.Nacos.DataId,
+		Group:  global.Nacos.Group,
+		OnChange: func(namespace, group, dataId, data string) {
+			zap.L().Info("应用配置发生变更", zap.String("dataId", dataId))
+			reloadAppConfig(data)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	zap.L().Info("开始监听应用配置", zap.String("dataId", global.Nacos.DataId))
+	return nil
+}
+
+// StopWatchAppConfig 取消对应用配置的监听
+func StopWatchAppConfig() {
+	if global.NacosClient == nil || global.Nacos == nil {
+		return
+	}
+	err := global.NacosClient.CancelListenConfig(vo.ConfigParam{
+		DataId: global.Nacos.DataId,
+		Group:  global.Nacos.Group,
+	})
+	if err != nil {
+		zap.L().Error("取消监听应用配置失败", zap.Error(err))
+	}
+}
+
+// reloadAppConfig 解析新配置并整体替换，再回调发生变化的分组
+func reloadAppConfig(content string) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	newConf, err := configloader.Parse(content)
+	if err != nil {
+		zap.L().Error("解析变更后的应用配置失败，保持当前配置", zap.Error(err))
+		return
+	}
+	if _, err := configloader.ApplyEnvOverrides(newConf); err != nil {
+		zap.L().Error("应用环境变量覆盖失败，保持当前配置", zap.Error(err))
+		return
+	}
+	if err := validateAppConfig(newConf); err != nil {
+		zap.L().Error("变更后的应用配置校验失败，保持当前配置", zap.Error(err))
+		return
+	}
+	saveConfigSnapshot(content)
+	oldConf := global.Config()
+	changed := changedConfigKeys(oldConf, newConf)
+	if len(changed) == 0 {
+		zap.L().Info("应用配置内容未变化")
+		return
+	}
+	// 只记录字段名，避免把密码写进日志
+	zap.L().Info("应用配置已更新", zap.Strings("changed", changed))
+	global.SetConfig(newConf)
+
+	callbacksMu.Lock()
+	callbacks := append([]configCallback(nil), configCallbacks...)
+	callbacksMu.Unlock()
+	for _, cb := range callbacks {
+		if !sectionChanged(cb.section, changed) {
+			continue
+		}
+		if err := cb.fn(oldConf, newConf); err != nil {
+			zap.L().Error("配置变更回调失败", zap.String("callback", cb.name), zap.Error(err))
+			continue
+		}
+		zap.L().Info("配置变更回调完成", zap.String("callback", cb.name))
+	}
+}
+
+// changedConfigKeys 按 JSON 字段名比较两份配置，返回发生变化的字段
+func changedConfigKeys(oldConf, newConf *appconf.AppConfigMaster) []string {
+	oldFields, newFields := configFields(oldConf), configFields(newConf)
+	var changed []string
+	for k, v := range newFields {
+		if !reflect.DeepEqual(oldFields[k], v) {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func configFields(conf *appconf.AppConfigMaster) map[string]interface{} {
+	fields := make(map[string]interface{})
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
+
+func sectionChanged(section string, changed []string) bool {
+	for _, key := range changed {
+		if strings.HasPrefix(key, section) {
+			return true
+		}
+	}
+	return false
+}
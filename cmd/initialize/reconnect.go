@@ -0,0 +1,109 @@
+package initialize
+
+import (
+	"context"
+	"fmt"
+	"go-task-service/cmd/appconf"
+	"go-task-service/cmd/global"
+	"go-task-service/core/mq"
+	"go.uber.org/zap"
+	"sync"
+)
+
+var reconnectorsOnce sync.Once
+
+// registerReconnectors 注册各客户端的重连回调，对应配置分组变化时用新配置建立连接，
+// 新连接可用后才替换全局客户端，失败时继续使用旧连接
+func registerReconnectors() {
+	reconnectorsOnce.Do(func() {
+		OnConfigChange(SectionMySQL, "mysql", reconnectMysql)
+		OnConfigChange(SectionRedis, "redis", reconnectRedis)
+		OnConfigChange(SectionElasticsearch, "elasticsearch", reconnectElasticsearch)
+		OnConfigChange(SectionRocketMQ, "rocketmq", reconnectRocketmq)
+		OnConfigChange(SectionMongoDB, "mongodb", reconnectMongoDB)
+	})
+}
+
+// 旧连接通过 global.RetireClient 关闭，等替换前开始的任务、消息处理和请求结束后才关闭
+
+func reconnectMysql(_, newConf *appconf.AppConfigMaster) error {
+	db, err := openMysql(newConf)
+	if err != nil {
+		return err
+	}
+	old := global.SetDB(db)
+	zap.L().Info("MySQL已使用新配置重新连接")
+	if old != nil {
+		if sqlDB, err := old.DB(); err == nil {
+			global.RetireClient("mysql", sqlDB.Close)
+		}
+	}
+	return nil
+}
+
+func reconnectRedis(_, newConf *appconf.AppConfigMaster) error {
+	client, err := newRedisClient(newConf)
+	if err != nil {
+		return err
+	}
+	old := global.SetRedisDB(client)
+	zap.L().Info("Redis已使用新配置重新连接")
+	if old != nil {
+		global.RetireClient("redis", old.Close)
+	}
+	return nil
+}
+
+func reconnectElasticsearch(_, newConf *appconf.AppConfigMaster) error {
+	client, err := newElasticsearchClient(newConf)
+	if err != nil {
+		return err
+	}
+	// Elasticsearch 客户端基于 http.Client，没有需要关闭的连接
+	global.SetESClient(client)
+	zap.L().Info("Elasticsearch已使用新配置重新连接")
+	return nil
+}
+
+func reconnectMongoDB(_, newConf *appconf.AppConfigMaster) error {
+	client, err := newMongoClient(newConf)
+	if err != nil {
+		return err
+	}
+	old := global.SetMongoDB(client.Database(newConf.MONGODATABASE))
+	zap.L().Info("MongoDB已使用新配置重新连接")
+	if old != nil {
+		global.RetireClient("mongodb", func() error {
+			return old.Client().Disconnect(context.Background())
+		})
+	}
+	return nil
+}
+
+// reconnectRocketmq 用新的实例名创建生产者和消费者，在新消费者上重新订阅所有已注册的 topic，
+// 两者都启动成功后才替换全局实例，旧实例由 mq.ReplaceClients 等使用方结束后关闭
+func reconnectRocketmq(_, newConf *appconf.AppConfigMaster) error {
+	p, err := newRocketmqProducer(newConf, nextRocketmqInstanceName())
+	if err != nil {
+		return err
+	}
+	if err := p.Start(); err != nil {
+		return fmt.Errorf("启动rocketmq生产者失败: %w", err)
+	}
+	c, err := newRocketmqConsumer(newConf, nextRocketmqInstanceName())
+	if err != nil {
+		_ = p.Shutdown()
+		return err
+	}
+	if err := mq.SubscribeAndStart(c); err != nil {
+		_ = c.Shutdown()
+		_ = p.Shutdown()
+		return err
+	}
+	mq.ReplaceClients(p, c)
+	zap.L().Info("rocketmq已使用新配置重新连接",
+		zap.Strings("name_servers", newConf.ROCKETMQNAMESERVERS),
+		zap.String("namespace", newConf.ROCKETMQNAMESPACE),
+	)
+	return nil
+}
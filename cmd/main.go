@@ -1,18 +1,90 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
-	_ "go-task-service/cmd/initialize"
+	"context"
+	"errors"
+	"go-task-service/cmd/global"
+	"go-task-service/cmd/initialize"
+	"go-task-service/consumers"
+	"go-task-service/core/mq"
+	"go-task-service/router"
 	"go-task-service/scheduler"
 	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+// 未配置 SHUTDOWN_TIMEOUT 时等待任务结束的默认时间
+const defaultShutdownTimeout = 30 * time.Second
+
 func main() {
 	// 初始化定时任务调度器
 	scheduler.InitScheduler()
 
-	r := gin.Default()
+	// 启动发件箱投递协程
+	if err := mq.StartOutboxRelay(); err != nil {
+		log.Fatalf("启动发件箱投递协程失败: %v", err)
+	}
+
+	// 订阅已注册的 topic 并启动消费者
+	if err := consumers.Setup(); err != nil {
+		log.Fatalf("初始化消息消费者失败: %v", err)
+	}
+	if err := mq.StartConsumer(); err != nil {
+		log.Fatalf("启动消息消费者失败: %v", err)
+	}
 
+	// 监听 nacos 配置变更，失败时使用启动时的配置继续运行
+	if err := initialize.WatchAppConfig(); err != nil {
+		log.Println("监听应用配置失败:", err)
+	}
+
+	// 初始化路由
+	r := router.InitRouter()
+	srv := &http.Server{Addr: ":8082", Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("HTTP 服务启动失败: %v", err)
+		}
+	}()
 	log.Println("定时任务服务已启动，监听端口 8082")
-	r.Run(":8082")
+
+	// 等待退出信号
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	stop()
+	log.Println("收到退出信号，开始优雅退出")
+	// 退出过程中不再处理配置变更，避免重建连接
+	initialize.StopWatchAppConfig()
+
+	timeout := defaultShutdownTimeout
+	if conf := global.Config(); conf.SHUTDOWNTIMEOUT > 0 {
+		timeout = time.Duration(conf.SHUTDOWNTIMEOUT) * time.Second
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// 先停止接收 HTTP 请求和消息，再停止调度并等待正在执行的任务
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("HTTP 服务关闭失败:", err)
+	}
+	if err := mq.ShutdownConsumer(shutdownCtx); err != nil {
+		log.Println("关闭消息消费者失败:", err)
+	}
+	if err := scheduler.Shutdown(shutdownCtx); err != nil {
+		log.Println("等待定时任务结束超时:", err)
+	}
+	// 任务停止后不会再写入发件箱，停止投递后再关闭生产者，超时后中断正在进行的发送
+	if err := mq.StopOutboxRelay(shutdownCtx); err != nil {
+		log.Println("等待发件箱投递结束超时:", err)
+	}
+
+	// 最后关闭各个客户端，单独给一段时间，避免前面超时后无法正常断开
+	closeCtx, closeCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer closeCancel()
+	initialize.Close(closeCtx)
+	log.Println("定时任务服务已退出")
 }
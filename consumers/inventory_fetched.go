@@ -0,0 +1,156 @@
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"go-task-service/cmd/global"
+	"go-task-service/core/auxiliary_method"
+	"go-task-service/core/curd_methods"
+	"go-task-service/core/mq"
+	"go-task-service/core/repository/mongodb_methods"
+	"go-task-service/core/tools"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"strconv"
+	"time"
+)
+
+func init() {
+	mq.MustHandle(mq.InventoryFetchedTopic, "*", HandleInventoryFetched, mq.WithConcurrency(8))
+}
+
+// Setup 同步消费者用到的表结构和索引，需在启动消费者之前调用
+func Setup() error {
+	if err := curd_methods.AutoMigrateInventoryReconcileRecord(); err != nil {
+		return err
+	}
+	// steam_aid 唯一，按抓取时间条件 upsert 时旧快照才会因冲突失败，而不是插入一份重复文档
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return steamInventoryRepo().EnsureIndexes(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "steam_aid", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+}
+
+func steamInventoryRepo() *mongodb_methods.MongoRepo[mongodb_methods.YYMSteamInventory] {
+	return &mongodb_methods.MongoRepo[mongodb_methods.YYMSteamInventory]{
+		Collection: global.MongoDB().Collection(mongodb_methods.SteamInventoryCollection),
+	}
+}
+
+// HandleInventoryFetched 保存抓取到的 steam 库存快照，并刷新对应库存的 updated_at
+func HandleInventoryFetched(ctx context.Context, payload *mq.InventoryFetchedMessage, msg *primitive.MessageExt) error {
+	ctx = tools.WithTraceID(ctx, payload.Trace.TraceID)
+	if payload.Error != "" {
+		// 抓取失败时不保存也不对账，清除入队标记让下一次扫描重新入队
+		zap.L().Warn("库存抓取失败，跳过", zap.Int64("steam_aid", payload.SteamAID), zap.String("error", payload.Error))
+		clearQueued(ctx, payload.SteamAID)
+		return nil
+	}
+	inventory, err := validateInventory(payload)
+	if err != nil {
+		return mq.Permanent(err)
+	}
+
+	if !inventory.Complete() {
+		// 不完整的快照会把缺失的物品误判为已转出，也不能覆盖已保存的完整快照，不保存不对账
+		zap.L().Warn("库存快照不完整，跳过", zap.Int64("steam_aid", payload.SteamAID), zap.Int("assets", len(inventory.Assets)))
+		clearQueued(ctx, payload.SteamAID)
+		return nil
+	}
+
+	steamAID := strconv.FormatInt(payload.SteamAID, 10)
+	// 消息乱序时不用旧快照覆盖新快照。条件写在 upsert 的 filter 中保证原子性：已有更新的快照时
+	// filter 匹配不到，插入会与 steam_aid 唯一索引冲突。用 $lte 使同一快照重投时仍能完成后续对账
+	filter := bson.M{
+		"steam_aid": steamAID,
+		"$or": bson.A{
+			bson.M{"fetched_at": bson.M{"$lte": payload.FetchedAt}},
+			bson.M{"fetched_at": bson.M{"$exists": false}},
+		},
+	}
+	err = steamInventoryRepo().Upsert(ctx, filter, mongodb_methods.YYMSteamInventory{
+		SteamAID:      steamAID,
+		InventoryDesc: string(payload.Inventory),
+		AssetCount:    len(inventory.Assets),
+		FetchedAt:     payload.FetchedAt,
+		UpdatedAt:     time.Now(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		zap.L().Info("收到过期的库存快照，跳过", zap.Int64("steam_aid", payload.SteamAID), zap.Time("fetched_at", payload.FetchedAt))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("保存库存快照失败: %w", err)
+	}
+
+	// 核对库存表中该账号的物品，先于刷新 updated_at，对账失败时消息重投
+	result, err := auxiliary_method.ReconcileBoxInventory(ctx, payload.SteamAID, inventory, payload.FetchedAt)
+	if err != nil {
+		return fmt.Errorf("库存对账失败: %w", err)
+	}
+	if result.Removed+result.Added+result.TradableTimeChanged > 0 {
+		zap.L().Info("库存对账发现差异",
+			zap.Int64("steam_aid", payload.SteamAID),
+			zap.Int("removed", result.Removed),
+			zap.Int("added", result.Added),
+			zap.Int("tradable_time_changed", result.TradableTimeChanged),
+		)
+	}
+
+	err = curd_methods.ExecuteTransaction(ctx, func(tx *gorm.DB) error {
+		return curd_methods.TouchBoxInventoryBySteamAID(payload.SteamAID, tx)
+	})
+	if err != nil {
+		return fmt.Errorf("刷新库存更新时间失败: %w", err)
+	}
+
+	// 刷新完成，允许该账号再次入队
+	clearQueued(ctx, payload.SteamAID)
+	zap.L().Info("库存快照保存成功", zap.Int64("steam_aid", payload.SteamAID), zap.Int("assets", len(inventory.Assets)))
+	return nil
+}
+
+// clearQueued 清除账号的库存刷新入队标记
+func clearQueued(ctx context.Context, steamAID int64) {
+	if err := auxiliary_method.ClearInventoryRefreshQueued(ctx, steamAID); err != nil {
+		zap.L().Error("清除库存刷新入队标记失败", zap.Int64("steam_aid", steamAID), zap.Error(err))
+	}
+}
+
+// validateInventory 校验库存快照：账号、抓取时间必填，每个 asset 都要有对应的 description
+func validateInventory(payload *mq.InventoryFetchedMessage) (*tools.Inventory, error) {
+	if payload.SteamAID <= 0 {
+		return nil, errors.New("steam_aid 不能为空")
+	}
+	if payload.FetchedAt.IsZero() {
+		return nil, errors.New("fetched_at 不能为空")
+	}
+	if len(payload.Inventory) == 0 {
+		return nil, errors.New("inventory 不能为空")
+	}
+	var inventory tools.Inventory
+	if err := json.Unmarshal(payload.Inventory, &inventory); err != nil {
+		return nil, fmt.Errorf("解析 inventory 失败: %w", err)
+	}
+	descriptions := make(map[string]struct{}, len(inventory.Descriptions))
+	for _, d := range inventory.Descriptions {
+		descriptions[d.ClassID+"_"+d.InstanceID] = struct{}{}
+	}
+	for _, a := range inventory.Assets {
+		if a.AssetID == "" || a.ClassID == "" {
+			return nil, fmt.Errorf("asset 缺少 assetid 或 classid: %+v", a)
+		}
+		if _, ok := descriptions[a.ClassID+"_"+a.InstanceID]; !ok {
+			return nil, fmt.Errorf("asset %s 缺少对应的 description", a.AssetID)
+		}
+	}
+	return &inventory, nil
+}
@@ -0,0 +1,169 @@
+package auxiliary_method
+
+import (
+	"context"
+	"go-task-service/cmd/global"
+	"go-task-service/core/curd_methods"
+	"go-task-service/core/tools"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// steam 返回的 tradable_time 可能出现的格式
+var tradableTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+}
+
+// ReconcileResult 一次对账的结果
+type ReconcileResult struct {
+	Removed             int `json:"removed"`
+	Added               int `json:"added"`
+	TradableTimeChanged int `json:"tradable_time_changed"`
+}
+
+// ReconcileBoxInventory 用 steam 库存快照核对该账号在库存表中的记录：
+// steam 中已消失且仍在售的库存按 INVENTORY_UNAVAILABLE_STATUS 标记为不可售，新出现的物品和可交易时间变化只记录差异。
+// 已记录过的消失和新增物品不重复记录。所有修改和差异记录在同一个事务中写入，调用方需保证快照完整
+func ReconcileBoxInventory(ctx context.Context, steamAID int64, inventory *tools.Inventory, fetchedAt time.Time) (ReconcileResult, error) {
+	var result ReconcileResult
+	traceID := tools.TraceIDFromContext(ctx)
+	unavailableStatus := global.Config().INVENTORYUNAVAILABLESTATUS
+
+	// 快照中每个 asset 的可交易时间
+	tradableTimes := make(map[string]*string, len(inventory.Descriptions))
+	for _, d := range inventory.Descriptions {
+		tradableTimes[d.ClassID+"_"+d.InstanceID] = d.TradableTime
+	}
+	steamAssets := make(map[string]*string, len(inventory.Assets))
+	for _, a := range inventory.Assets {
+		steamAssets[a.AssetID] = tradableTimes[a.ClassID+"_"+a.InstanceID]
+	}
+
+	err := curd_methods.ExecuteTransaction(ctx, func(tx *gorm.DB) error {
+		tx = tx.WithContext(ctx)
+		rows, err := curd_methods.QueryBoxInventoryAssetsBySteamAID(steamAID, tx)
+		if err != nil {
+			return err
+		}
+		newRecord := func(inventoryID int, assetID, changeType, oldValue, newValue string) curd_methods.YYMInventoryReconcileRecord {
+			return curd_methods.YYMInventoryReconcileRecord{
+				SteamAID:    steamAID,
+				InventoryID: inventoryID,
+				AssetID:     assetID,
+				ChangeType:  changeType,
+				OldValue:    oldValue,
+				NewValue:    newValue,
+				FetchedAt:   fetchedAt,
+				TraceID:     traceID,
+			}
+		}
+
+		var records []curd_methods.YYMInventoryReconcileRecord
+		var removed []curd_methods.BoxInventoryAsset
+		known := make(map[string]struct{}, len(rows))
+		for _, row := range rows {
+			if row.AssetID == "" {
+				continue
+			}
+			known[row.AssetID] = struct{}{}
+
+			steamTradable, ok := steamAssets[row.AssetID]
+			if !ok {
+				// 只处理仍在售的库存，已售出的库存从账号中消失是正常的
+				if row.SellStatus == curd_methods.InventoryStatusOnSale {
+					removed = append(removed, row)
+				}
+				continue
+			}
+
+			newTradable := parseTradableTime(steamTradable)
+			if !sameTime(row.TradableTime, newTradable) {
+				if err := curd_methods.UpdateInventoryTradableTime(row.ID, newTradable, tx); err != nil {
+					return err
+				}
+				records = append(records, newRecord(row.ID, row.AssetID, curd_methods.ReconcileChangeTradableTime,
+					formatTime(row.TradableTime), formatTime(newTradable)))
+				result.TradableTimeChanged++
+			}
+		}
+
+		// 未配置不可售状态时库存保持在售，下次快照仍会出现，需按已有记录去重
+		recordedRemoved := make(map[string]struct{})
+		if unavailableStatus == 0 {
+			removedIDs := make([]string, 0, len(removed))
+			for _, row := range removed {
+				removedIDs = append(removedIDs, row.AssetID)
+			}
+			recordedRemoved, err = curd_methods.QueryReconciledAssetIDs(steamAID, curd_methods.ReconcileChangeRemoved, removedIDs, tx)
+			if err != nil {
+				return err
+			}
+		}
+		for _, row := range removed {
+			if _, ok := recordedRemoved[row.AssetID]; ok {
+				continue
+			}
+			newValue := ""
+			if unavailableStatus != 0 {
+				if err := curd_methods.UpdateInventoryStatus(row.ID, unavailableStatus, tx); err != nil {
+					return err
+				}
+				newValue = strconv.Itoa(unavailableStatus)
+			}
+			records = append(records, newRecord(row.ID, row.AssetID, curd_methods.ReconcileChangeRemoved,
+				strconv.Itoa(row.SellStatus), newValue))
+			result.Removed++
+		}
+
+		var addedIDs []string
+		for assetID := range steamAssets {
+			if _, ok := known[assetID]; !ok {
+				addedIDs = append(addedIDs, assetID)
+			}
+		}
+		recordedAdded, err := curd_methods.QueryReconciledAssetIDs(steamAID, curd_methods.ReconcileChangeAdded, addedIDs, tx)
+		if err != nil {
+			return err
+		}
+		for _, assetID := range addedIDs {
+			if _, ok := recordedAdded[assetID]; ok {
+				continue
+			}
+			records = append(records, newRecord(0, assetID, curd_methods.ReconcileChangeAdded, "", formatTime(parseTradableTime(steamAssets[assetID]))))
+			result.Added++
+		}
+		return curd_methods.AddInventoryReconcileRecords(records, tx)
+	})
+	return result, err
+}
+
+// parseTradableTime 解析 steam 的可交易时间，为空或无法解析时返回 nil（立即可交易）
+func parseTradableTime(value *string) *time.Time {
+	if value == nil || *value == "" {
+		return nil
+	}
+	for _, layout := range tradableTimeLayouts {
+		if t, err := time.ParseInLocation(layout, *value, time.Local); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,79 @@
+package auxiliary_method
+
+import (
+	"context"
+	"go-task-service/cmd/global"
+	"go-task-service/core/tools"
+	"strconv"
+	"time"
+)
+
+// 库存刷新入队标记的 key 前缀，值为入队时间
+const inventoryRefreshQueuedPrefix = "inventory:refresh:queued:"
+
+// 未配置 INVENTORY_REFRESH_TTL 且调用方无法给出扫描间隔时入队标记的默认有效期，
+// 需覆盖 update_inventory 默认的 6 小时扫描间隔和 30 分钟执行超时，否则积压时下一次扫描会重复入队
+const DefaultInventoryRefreshTTL = 6*time.Hour + 30*time.Minute
+
+func inventoryRefreshQueuedKey(steamAID int64) string {
+	return inventoryRefreshQueuedPrefix + strconv.FormatInt(steamAID, 10)
+}
+
+// InventoryRefreshTTL 入队标记的有效期，超过该时间仍未刷新完成的账号会被再次入队。
+// 优先使用 INVENTORY_REFRESH_TTL，未配置时使用调用方按扫描间隔算出的 fallback，两者都没有时使用默认值
+func InventoryRefreshTTL(fallback time.Duration) time.Duration {
+	if ttl := global.Config().INVENTORYREFRESHTTL; ttl > 0 {
+		return time.Duration(ttl) * time.Second
+	}
+	if fallback > 0 {
+		return fallback
+	}
+	return DefaultInventoryRefreshTTL
+}
+
+// MarkInventoryRefreshQueued 标记 steam 账号已进入刷新队列，ttl 为标记有效期，返回 false 表示该账号已在队列中
+func MarkInventoryRefreshQueued(ctx context.Context, steamAID int64, ttl time.Duration) (bool, error) {
+	return tools.Lock(ctx, inventoryRefreshQueuedKey(steamAID), strconv.FormatInt(time.Now().Unix(), 10), ttl)
+}
+
+// ClearInventoryRefreshQueued 账号刷新完成或发送失败后清除入队标记
+func ClearInventoryRefreshQueued(ctx context.Context, steamAID int64) error {
+	return global.RedisDB().Del(inventoryRefreshQueuedKey(steamAID)).Err()
+}
+
+// 发送失败的 steam 账号集合，下一次扫描优先处理
+const inventoryRefreshFailedKey = "inventory:refresh:failed"
+
+// SaveFailedInventoryRefresh 记录发送失败的 steam 账号
+func SaveFailedInventoryRefresh(ctx context.Context, steamAIDs []int64) error {
+	if len(steamAIDs) == 0 {
+		return nil
+	}
+	members := make([]interface{}, 0, len(steamAIDs))
+	for _, steamAID := range steamAIDs {
+		members = append(members, steamAID)
+	}
+	return global.RedisDB().SAdd(inventoryRefreshFailedKey, members...).Err()
+}
+
+// QueryFailedInventoryRefresh 查询上一次发送失败的 steam 账号
+func QueryFailedInventoryRefresh(ctx context.Context) ([]int64, error) {
+	members, err := global.RedisDB().SMembers(inventoryRefreshFailedKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	steamAIDs := make([]int64, 0, len(members))
+	for _, member := range members {
+		steamAID, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		steamAIDs = append(steamAIDs, steamAID)
+	}
+	return steamAIDs, nil
+}
+
+// RemoveFailedInventoryRefresh 重新发送成功后从失败集合中移除
+func RemoveFailedInventoryRefresh(ctx context.Context, steamAID int64) error {
+	return global.RedisDB().SRem(inventoryRefreshFailedKey, steamAID).Err()
+}
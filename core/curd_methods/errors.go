@@ -0,0 +1,39 @@
+package curd_methods
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// 可重试的 MySQL 错误码
+var transientMySQLErrors = map[uint16]bool{
+	1040: true, // Too many connections
+	1205: true, // Lock wait timeout exceeded
+	1213: true, // Deadlock found
+	2006: true, // MySQL server has gone away
+	2013: true, // Lost connection to MySQL server
+}
+
+// IsTransientDBError 判断是否为连接断开、锁等待超时、死锁等可重试的临时错误
+func IsTransientDBError(err error) bool {
+	if err == nil {
+		return false
+	}
+	// context.DeadlineExceeded 也实现了 net.Error，超时和取消都由调用方决定，不在这里重试
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return transientMySQLErrors[mysqlErr.Number]
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,43 @@
+package curd_methods
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestIsTransientDBError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"bad conn", driver.ErrBadConn, true},
+		{"invalid conn", mysql.ErrInvalidConn, true},
+		{"wrapped bad conn", fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{"deadlock", &mysql.MySQLError{Number: 1213}, true},
+		{"lock wait timeout", &mysql.MySQLError{Number: 1205}, true},
+		{"too many connections", &mysql.MySQLError{Number: 1040}, true},
+		{"server gone away", &mysql.MySQLError{Number: 2006}, true},
+		{"duplicate entry", &mysql.MySQLError{Number: 1062}, false},
+		{"syntax error", &mysql.MySQLError{Number: 1064}, false},
+		{"net error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"deadline exceeded", context.DeadlineExceeded, false},
+		{"wrapped deadline exceeded", fmt.Errorf("query: %w", context.DeadlineExceeded), false},
+		{"canceled", context.Canceled, false},
+		{"other", errors.New("record not found"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransientDBError(tt.err); got != tt.want {
+				t.Errorf("IsTransientDBError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -12,7 +12,7 @@ import (
 
 // 定义一个通用的事务执行函数，接受一个处理函数作为参数
 func ExecuteTransaction(ctx context.Context, f func(tx *gorm.DB) error) error {
-	db := global.DB
+	db := global.DB()
 	tx := db.Begin()
 	if tx.Error != nil {
 		return tx.Error
@@ -41,13 +41,13 @@ func AddSellerGoodSku(SellerGood models.YYMSellerGoodsSKU, tx *gorm.DB) (models.
 
 // 删除卖家商品的某个sku
 func DeleteSellerGoodSku(skuId int) error {
-	err := global.DB.Where("id = ?", skuId).Delete(&models.YYMSellerGoodsSKU{}).Error
+	err := global.DB().Where("id = ?", skuId).Delete(&models.YYMSellerGoodsSKU{}).Error
 	return err
 }
 
 // 修改卖家商品sku的价格
 func UpdateSellerGoodSkuPrice(skuId int, price float64) error {
-	err := global.DB.Model(&models.YYMSellerGoodsSKU{}).Where("id = ?", skuId).Update("price", price).Error
+	err := global.DB().Model(&models.YYMSellerGoodsSKU{}).Where("id = ?", skuId).Update("price", price).Error
 	return err
 }
 
@@ -59,13 +59,13 @@ func AddSellerGood(sellerGood models.YYMSellerGoods, tx *gorm.DB) (models.YYMSel
 
 // 修改卖家商品表当中的上下架状态
 func UpdateSellerGoodStatus(sellerGood models.YYMSellerGoods) error {
-	err := global.DB.Model(&models.YYMSellerGoods{}).Where("id = ?", sellerGood.ID).Update("is_listing", sellerGood.IsListing).Error
+	err := global.DB().Model(&models.YYMSellerGoods{}).Where("id = ?", sellerGood.ID).Update("is_listing", sellerGood.IsListing).Error
 	return err
 }
 
 // 修改卖家商品的名称
 func UpdateSellerGoodName(sellerGood models.YYMSellerGoods) error {
-	err := global.DB.Model(&models.YYMSellerGoods{}).Where("id = ?", sellerGood.ID).Update("goods_name", sellerGood.GoodName).Error
+	err := global.DB().Model(&models.YYMSellerGoods{}).Where("id = ?", sellerGood.ID).Update("goods_name", sellerGood.GoodName).Error
 	return err
 }
 
@@ -73,14 +73,14 @@ func UpdateSellerGoodName(sellerGood models.YYMSellerGoods) error {
 
 // 添加一条库存记录
 func AddInventoryLog(inventoryLog models.YYMCSGOBoxInventory) (models.YYMCSGOBoxInventory, error) {
-	err := global.DB.Create(&inventoryLog).Error
+	err := global.DB().Create(&inventoryLog).Error
 	return inventoryLog, err
 }
 
 // 根据skuCode去查询masterGoods
 func GetMasterGoodsSkuByCode(skuCode string) (models.YYMGoodsSKU, error) {
 	var masterGood models.YYMGoodsSKU
-	err := global.DB.Where("sku_code = ?", skuCode).Find(&masterGood).Limit(1).Error
+	err := global.DB().Where("sku_code = ?", skuCode).Find(&masterGood).Limit(1).Error
 	return masterGood, err
 }
 
@@ -93,7 +93,7 @@ func AddMasterGoodSSku(masterGoodSSku models.YYMGoodsSKU, tx *gorm.DB) (models.Y
 // 根据属性id 查询出该记录  并推算出他的父级id
 func GetAttributeById(attributeId int) (models.YYMAttribute, error) {
 	var attribute models.YYMAttribute
-	err := global.DB.Where("id = ?", attributeId).Find(&attribute).Error
+	err := global.DB().Where("id = ?", attributeId).Find(&attribute).Error
 	return attribute, err
 }
 
@@ -112,82 +112,82 @@ func AddSellerGoodsGuarantee(sellerGoodsGuarantee models.YYMSellerGoodsGuarantee
 // 根据类别查询商品
 func GetGoodsByCategory(categoryId int) ([]models.YYMGoodsMaster, error) {
 	var goods []models.YYMGoodsMaster
-	err := global.DB.Where("category_id = ?", categoryId).Find(&goods).Error
+	err := global.DB().Where("category_id = ?", categoryId).Find(&goods).Error
 	return goods, err
 }
 
 // 根据pid查询出保障的属性
 func GetGuaranteeAttrByParentId(parentId int) ([]models.YYMGuaranteeAttribute, error) {
 	var attributes []models.YYMGuaranteeAttribute
-	err := global.DB.Where("parent_id =?", parentId).Find(&attributes).Error
+	err := global.DB().Where("parent_id =?", parentId).Find(&attributes).Error
 	return attributes, err
 }
 
 // 根据pid查询出属性值
 func GetAttrValueByParentId(parentId int) ([]models.YYMAttribute, error) {
 	var values []models.YYMAttribute
-	err := global.DB.Where("parent_id =?", parentId).Find(&values).Error
+	err := global.DB().Where("parent_id =?", parentId).Find(&values).Error
 	return values, err
 }
 
 // 根据id查询出卖家商品
 func GetSellerGoodsInfoById(id int) (models.YYMSellerGoods, error) {
 	var sellerGoods models.YYMSellerGoods
-	err := global.DB.Where("id = ?", id).Find(&sellerGoods).Error
+	err := global.DB().Where("id = ?", id).Find(&sellerGoods).Error
 	return sellerGoods, err
 }
 
 // 根据卖家商品id查询出该商品所有的sku
 func GetGoodsSkuById(id int) ([]models.YYMSellerGoodsSKU, error) {
 	var sellerGoodsSku []models.YYMSellerGoodsSKU
-	err := global.DB.Where("seller_goods_id =?", id).Find(&sellerGoodsSku).Error
+	err := global.DB().Where("seller_goods_id =?", id).Find(&sellerGoodsSku).Error
 	return sellerGoodsSku, err
 }
 
 // 根据商品ID查询出活动商品
 func GetActivityGoodsByGoodsId(goodsId int) (models.YYMSellerGoodsActivity, error) {
 	var activityGoods models.YYMSellerGoodsActivity
-	err := global.DB.Where("seller_good_sku_id =?", goodsId).Find(&activityGoods).Error
+	err := global.DB().Where("seller_good_sku_id =?", goodsId).Find(&activityGoods).Error
 	return activityGoods, err
 }
 
 // 根据ID查询出该商品的所有保障信息
 func GetGuaranteeGoodsByGoodsId(goodsId int) ([]models.YYMSellerGoodsGuarantee, error) {
 	var guaranteeGoods []models.YYMSellerGoodsGuarantee
-	err := global.DB.Where("seller_goods_id =?", goodsId).Find(&guaranteeGoods).Error
+	err := global.DB().Where("seller_goods_id =?", goodsId).Find(&guaranteeGoods).Error
 	return guaranteeGoods, err
 }
 
 // 根据保障ID查询出该保障信息
 func GetGuaranteeInfoById(id int) (models.YYMGuaranteeAttribute, error) {
 	var guaranteeInfo models.YYMGuaranteeAttribute
-	err := global.DB.Where("id =?", id).Find(&guaranteeInfo).Error
+	err := global.DB().Where("id =?", id).Find(&guaranteeInfo).Error
 	return guaranteeInfo, err
 }
 
 // 添加商品版本记录
 func AddGoodsVersion(goodsVersion models.YYMSellerGoodsVersionRecord) (models.YYMSellerGoodsVersionRecord, error) {
-	err := global.DB.Create(&goodsVersion).Error
+	err := global.DB().Create(&goodsVersion).Error
 	return goodsVersion, err
 }
 
 // 修改卖家商品的版本信息
 func UpdateSellerGoodsVersion(id int, version string) error {
-	err := global.DB.Model(&models.YYMSellerGoods{}).Where("id =?", id).Update("version_hash", version).Error
+	err := global.DB().Model(&models.YYMSellerGoods{}).Where("id =?", id).Update("version_hash", version).Error
 	return err
 }
 
 // 根据版本hash去查询表当中是否存在该版本
 func GetGoodsVersionByHash(version string) (models.YYMSellerGoodsVersionRecord, error) {
 	var goodsVersion models.YYMSellerGoodsVersionRecord
-	err := global.DB.Where("version_hash =?", version).Find(&goodsVersion).Error
+	err := global.DB().Where("version_hash =?", version).Find(&goodsVersion).Error
 	return goodsVersion, err
 }
 
 // 根据商品ID查询出商品详情
 func QueryMasterGoodInfo(goodID int) (models.YYMGoodsMaster, error) {
 	var good models.YYMGoodsMaster
-	err := global.DB.Where("id = ?", goodID).Find(&good).Error
+	err := global.DB().Where("id = ?", goodID).Find(&good).Error
 	return good, err
 }
 
@@ -212,14 +212,14 @@ func AddBoxOrder(boxOrder models.YYMBoxOrder, tx *gorm.DB) (models.YYMBoxOrder,
 // 根据sku_code和user_id查询出seller_goods_sku表当中的price
 func QuerySellerGoodsSkuBySkuCodeAndUserID(skuCode string, userId int) (models.YYMSellerGoodsSKU, error) {
 	var sku models.YYMSellerGoodsSKU
-	err := global.DB.Where("sku_code = ? and user_id = ?", skuCode, userId).Find(&sku).Error
+	err := global.DB().Where("sku_code = ? and user_id = ?", skuCode, userId).Find(&sku).Error
 	return sku, err
 }
 
 // 根据userID查询出该用户的信息
 func QueryUserInfoById(userId int) (models.User, error) {
 	var user models.User
-	err := global.DB.Where("id = ?", userId).Find(&user).Error
+	err := global.DB().Where("id = ?", userId).Find(&user).Error
 	return user, err
 }
 
@@ -256,42 +256,42 @@ func UpdateInventoryStatus(inventoryId int, status int, tx *gorm.DB) error {
 // 根据订单编号查询订单信息
 func QueryOrderInfoByOrderNo(orderNo string) (models.YYMOrderMaster, error) {
 	var order models.YYMOrderMaster
-	err := global.DB.Where("order_no = ?", orderNo).Find(&order).Error
+	err := global.DB().Where("order_no = ?", orderNo).Find(&order).Error
 	return order, err
 }
 
 // 查询库存信息
 func QueryInventoryInfoById(userId int) ([]models.YYMBoxInventory, error) {
 	var inventory []models.YYMBoxInventory
-	err := global.DB.Where("user_id = ?", userId).Preload("Steam").Find(&inventory).Error
+	err := global.DB().Where("user_id = ?", userId).Preload("Steam").Find(&inventory).Error
 	return inventory, err
 }
 
 // 根据steam账号查询出该账号的信息
 func QuerySteamAccountInfoById(steamAccountId int) (models.SteamAccount, error) {
 	var steamAccount models.SteamAccount
-	err := global.DB.Where("id = ?", steamAccountId).Find(&steamAccount).Error
+	err := global.DB().Where("id = ?", steamAccountId).Find(&steamAccount).Error
 	return steamAccount, err
 }
 
 // 根据user_id查询出该用户的钱包信息
 func QueryUserWalletById(userId int) (models.YYMWallet, error) {
 	var userWallet models.YYMWallet
-	err := global.DB.Where("user_id = ?", userId).Find(&userWallet).Error
+	err := global.DB().Where("user_id = ?", userId).Find(&userWallet).Error
 	return userWallet, err
 }
 
 // 根据sku_code和goods_id查询出该商品的库存
 func QueryInventoryBySkuCodeAndGoodsId(skuCode string, goodsId int) (models.YYMBoxInventory, error) {
 	var inventory models.YYMBoxInventory
-	err := global.DB.Where("sku_code = ? and goods_id = ? and sell_status = 0", skuCode, goodsId).Find(&inventory).Error
+	err := global.DB().Where("sku_code = ? and goods_id = ? and sell_status = 0", skuCode, goodsId).Find(&inventory).Error
 	return inventory, err
 }
 
 // 根据订单编号查询订单信息
 func QueryBoxOrderInfoByOrderNo(orderNo string) (models.YYMBoxOrder, error) {
 	var order models.YYMBoxOrder
-	err := global.DB.Where("order_no = ?", orderNo).Find(&order).Error
+	err := global.DB().Where("order_no = ?", orderNo).Find(&order).Error
 	return order, err
 }
 
@@ -300,9 +300,43 @@ func AddDeliveryRecord(deliveryRecord models.YYMShippingRecord, tx *gorm.DB) (mo
 	err := tx.Create(&deliveryRecord).Error
 	return deliveryRecord, err
 }
-func QueryOutdatedInventory() ([]models.YYMBoxInventory, error) {
-	var result []models.YYMBoxInventory
+
+// 默认每页查询的库存条数
+const DefaultInventoryPageSize = 500
+
+// 按 id 游标分页查询六小时内没有更新过的库存，每查到一页调用一次 fn，
+// 内存中最多只保留一页数据。fn 返回错误时停止查询并返回该错误
+func QueryOutdatedInventory(ctx context.Context, pageSize int, fn func(page []models.YYMBoxInventory) error) error {
+	if pageSize <= 0 {
+		pageSize = DefaultInventoryPageSize
+	}
 	sixHoursAgo := time.Now().Add(-6 * time.Hour)
-	err := global.DB.Where("updated_at < ?", sixHoursAgo).Find(&result).Error
-	return result, err
+	var lastID uint64
+	for {
+		var page []models.YYMBoxInventory
+		err := global.DB().WithContext(ctx).
+			Where("updated_at < ? and id > ?", sixHoursAgo, lastID).
+			Order("id asc").
+			Limit(pageSize).
+			Find(&page).Error
+		if err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		if len(page) < pageSize {
+			return nil
+		}
+		lastID = uint64(page[len(page)-1].ID)
+	}
+}
+
+// 刷新某个 steam 账号下所有库存的 updated_at，下一次过期库存扫描会跳过这些库存
+func TouchBoxInventoryBySteamAID(steamAID int64, tx *gorm.DB) error {
+	err := tx.Model(&models.YYMBoxInventory{}).Where("steam_aid = ?", steamAID).Update("updated_at", time.Now()).Error
+	return err
 }
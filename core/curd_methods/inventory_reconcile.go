@@ -0,0 +1,94 @@
+package curd_methods
+
+import (
+	"github.com/sbigtree/go-db-model/models"
+	"go-task-service/cmd/global"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 在售库存的 sell_status
+const InventoryStatusOnSale = 0
+
+// 对账差异类型
+const (
+	ReconcileChangeRemoved      = "removed"               // steam 中已不存在
+	ReconcileChangeAdded        = "added"                 // steam 中新出现，库存表中没有
+	ReconcileChangeTradableTime = "tradable_time_changed" // 可交易时间变化
+)
+
+// BoxInventoryAsset 对账需要的库存字段
+type BoxInventoryAsset struct {
+	ID           int        `gorm:"column:id"`
+	AssetID      string     `gorm:"column:asset_id"`
+	SellStatus   int        `gorm:"column:sell_status"`
+	TradableTime *time.Time `gorm:"column:tradable_time"`
+}
+
+// YYMInventoryReconcileRecord 库存对账差异记录表，每一处变更写入一条，用于审计
+type YYMInventoryReconcileRecord struct {
+	ID          int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	SteamAID    int64     `gorm:"column:steam_aid;not null;index:idx_steam_change,priority:1" json:"steam_aid"`
+	InventoryID int       `gorm:"column:inventory_id" json:"inventory_id"`
+	AssetID     string    `gorm:"column:asset_id;type:varchar(64);index:idx_steam_change,priority:3" json:"asset_id"`
+	ChangeType  string    `gorm:"column:change_type;type:varchar(32);not null;index:idx_steam_change,priority:2" json:"change_type"`
+	OldValue    string    `gorm:"column:old_value;type:varchar(255)" json:"old_value"`
+	NewValue    string    `gorm:"column:new_value;type:varchar(255)" json:"new_value"`
+	FetchedAt   time.Time `gorm:"column:fetched_at" json:"fetched_at"`
+	TraceID     string    `gorm:"column:trace_id;type:varchar(191)" json:"trace_id"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (YYMInventoryReconcileRecord) TableName() string {
+	return "yym_inventory_reconcile_record"
+}
+
+// 同步库存对账记录表结构
+func AutoMigrateInventoryReconcileRecord() error {
+	return global.DB().AutoMigrate(&YYMInventoryReconcileRecord{})
+}
+
+// 查询某个 steam 账号下的库存，加行锁避免对账期间被并发修改
+func QueryBoxInventoryAssetsBySteamAID(steamAID int64, tx *gorm.DB) ([]BoxInventoryAsset, error) {
+	var assets []BoxInventoryAsset
+	err := tx.Model(&models.YYMBoxInventory{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id, asset_id, sell_status, tradable_time").
+		Where("steam_aid = ?", steamAID).
+		Scan(&assets).Error
+	return assets, err
+}
+
+// 修改库存的可交易时间
+func UpdateInventoryTradableTime(inventoryId int, tradableTime *time.Time, tx *gorm.DB) error {
+	err := tx.Model(&models.YYMBoxInventory{}).Where("id = ?", inventoryId).Update("tradable_time", tradableTime).Error
+	return err
+}
+
+// 查询该账号已记录过的指定类型差异涉及的 asset_id，用于避免每次快照重复记录
+func QueryReconciledAssetIDs(steamAID int64, changeType string, assetIDs []string, tx *gorm.DB) (map[string]struct{}, error) {
+	recorded := make(map[string]struct{})
+	if len(assetIDs) == 0 {
+		return recorded, nil
+	}
+	var ids []string
+	err := tx.Model(&YYMInventoryReconcileRecord{}).
+		Where("steam_aid = ? and change_type = ? and asset_id in ?", steamAID, changeType, assetIDs).
+		Distinct().
+		Pluck("asset_id", &ids).Error
+	for _, id := range ids {
+		recorded[id] = struct{}{}
+	}
+	return recorded, err
+}
+
+// 批量写入对账差异记录
+func AddInventoryReconcileRecords(records []YYMInventoryReconcileRecord, tx *gorm.DB) error {
+	if len(records) == 0 {
+		return nil
+	}
+	err := tx.Create(&records).Error
+	return err
+}
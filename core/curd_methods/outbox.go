@@ -0,0 +1,120 @@
+package curd_methods
+
+import (
+	"context"
+	"go-task-service/cmd/global"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 发件箱消息状态
+const (
+	OutboxStatusPending = 0 // 待发送
+	OutboxStatusSent    = 1 // 已发送
+	OutboxStatusFailed  = 2 // 超过重试次数，放弃发送
+)
+
+// YYMOutboxMessage 事务发件箱表，业务数据和待发送消息在同一个事务中写入
+type YYMOutboxMessage struct {
+	ID            int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Topic         string     `gorm:"column:topic;type:varchar(255);not null" json:"topic"`
+	Tags          string     `gorm:"column:tags;type:varchar(255)" json:"tags"`
+	Keys          string     `gorm:"column:msg_keys;type:varchar(512)" json:"keys"`
+	Body          []byte     `gorm:"column:body;type:longblob" json:"body"`
+	Status        int        `gorm:"column:status;not null;default:0;index:idx_status_next,priority:1" json:"status"`
+	Attempts      int        `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	LastError     string     `gorm:"column:last_error;type:text" json:"last_error"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at;index:idx_status_next,priority:2" json:"next_attempt_at"`
+	ClaimToken    string     `gorm:"column:claim_token;type:varchar(64)" json:"claim_token"` // 当前认领该消息的投递批次
+	SentAt        *time.Time `gorm:"column:sent_at" json:"sent_at"`
+	CreatedAt     time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (YYMOutboxMessage) TableName() string {
+	return "yym_outbox_message"
+}
+
+// 同步发件箱表结构
+func AutoMigrateOutboxMessage() error {
+	return global.DB().AutoMigrate(&YYMOutboxMessage{})
+}
+
+// 在业务事务中写入一条待发送消息
+func AddOutboxMessage(message YYMOutboxMessage, tx *gorm.DB) (YYMOutboxMessage, error) {
+	message.Status = OutboxStatusPending
+	if message.NextAttemptAt.IsZero() {
+		message.NextAttemptAt = time.Now()
+	}
+	err := tx.Create(&message).Error
+	return message, err
+}
+
+// 认领一批到期的待发送消息：SKIP LOCKED 保证多个实例不会拿到同一条，认领后把 next_attempt_at
+// 推迟 lease 作为租约并写入 token，调用方提交事务后在事务外发送。实例在租约内宕机时，
+// 租约到期后消息会被重新认领（至少一次）
+func ClaimPendingOutboxMessages(ctx context.Context, limit int, token string, lease time.Duration, tx *gorm.DB) ([]YYMOutboxMessage, error) {
+	var messages []YYMOutboxMessage
+	now := time.Now()
+	err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? and next_attempt_at <= ?", OutboxStatusPending, now).
+		Order("id asc").
+		Limit(limit).
+		Find(&messages).Error
+	if err != nil || len(messages) == 0 {
+		return messages, err
+	}
+	ids := make([]int64, 0, len(messages))
+	for _, m := range messages {
+		ids = append(ids, m.ID)
+	}
+	err = tx.WithContext(ctx).Model(&YYMOutboxMessage{}).
+		Where("id in ?", ids).
+		Updates(map[string]interface{}{
+			"claim_token":     token,
+			"next_attempt_at": now.Add(lease),
+		}).Error
+	return messages, err
+}
+
+// 标记消息发送成功，只更新仍由 token 认领的消息，返回 false 表示租约已过期被其他批次认领
+func MarkOutboxMessageSent(id int64, token string, tx *gorm.DB) (bool, error) {
+	result := tx.Model(&YYMOutboxMessage{}).
+		Where("id = ? and claim_token = ?", id, token).
+		Updates(map[string]interface{}{
+			"status":      OutboxStatusSent,
+			"attempts":    gorm.Expr("attempts + 1"),
+			"sent_at":     time.Now(),
+			"claim_token": "",
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// 记录一次发送失败，status 为 OutboxStatusPending 时在 nextAttemptAt 后重试。
+// 只更新仍由 token 认领的消息，返回 false 表示租约已过期被其他批次认领
+func MarkOutboxMessageFailed(id int64, token string, status int, errText string, nextAttemptAt time.Time, tx *gorm.DB) (bool, error) {
+	result := tx.Model(&YYMOutboxMessage{}).
+		Where("id = ? and claim_token = ?", id, token).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"attempts":        gorm.Expr("attempts + 1"),
+			"last_error":      errText,
+			"next_attempt_at": nextAttemptAt,
+			"claim_token":     "",
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// 释放 token 认领但还没有处理的消息，使其可以立即被重新认领，投递协程退出时使用
+func ReleaseOutboxClaim(token string, tx *gorm.DB) (int64, error) {
+	result := tx.Model(&YYMOutboxMessage{}).
+		Where("claim_token = ? and status = ?", token, OutboxStatusPending).
+		Updates(map[string]interface{}{
+			"next_attempt_at": time.Now(),
+			"claim_token":     "",
+		})
+	return result.RowsAffected, result.Error
+}
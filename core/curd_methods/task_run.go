@@ -0,0 +1,73 @@
+package curd_methods
+
+import (
+	"go-task-service/cmd/global"
+	"time"
+)
+
+// 定时任务执行状态
+const (
+	TaskRunStatusRunning = "running"
+	TaskRunStatusSuccess = "success"
+	TaskRunStatusFailed  = "failed"
+	TaskRunStatusSkipped = "skipped"
+)
+
+// YYMTaskRunRecord 定时任务执行记录表，每次任务触发写入一条
+type YYMTaskRunRecord struct {
+	ID             int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	TaskName       string     `gorm:"column:task_name;type:varchar(128);not null;index:idx_task_started,priority:1" json:"task_name"`
+	EntryID        int        `gorm:"column:entry_id" json:"entry_id"`
+	FireID         string     `gorm:"column:fire_id;type:varchar(191);index" json:"fire_id"`
+	FireTime       time.Time  `gorm:"column:fire_time" json:"fire_time"`
+	Attempt        int        `gorm:"column:attempt;default:1" json:"attempt"`
+	Instance       string     `gorm:"column:instance;type:varchar(128)" json:"instance"`
+	StartedAt      time.Time  `gorm:"column:started_at;not null;index:idx_task_started,priority:2" json:"started_at"`
+	EndedAt        *time.Time `gorm:"column:ended_at" json:"ended_at"`
+	DurationMs     int64      `gorm:"column:duration_ms" json:"duration_ms"`
+	Status         string     `gorm:"column:status;type:varchar(16);not null;index" json:"status"`
+	ErrorText      string     `gorm:"column:error_text;type:text" json:"error_text"`
+	ItemsProcessed int64      `gorm:"column:items_processed" json:"items_processed"`
+	CreatedAt      time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (YYMTaskRunRecord) TableName() string {
+	return "yym_task_run_record"
+}
+
+// 同步定时任务执行记录表结构
+func AutoMigrateTaskRunRecord() error {
+	return global.DB().AutoMigrate(&YYMTaskRunRecord{})
+}
+
+// 添加一条任务执行记录
+func AddTaskRunRecord(record YYMTaskRunRecord) (YYMTaskRunRecord, error) {
+	err := global.DB().Create(&record).Error
+	return record, err
+}
+
+// 任务结束后回写执行结果
+func FinishTaskRunRecord(id int64, status string, errText string, itemsProcessed int64, endedAt time.Time, duration time.Duration) error {
+	err := global.DB().Model(&YYMTaskRunRecord{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"error_text":      errText,
+			"items_processed": itemsProcessed,
+			"ended_at":        endedAt,
+			"duration_ms":     duration.Milliseconds(),
+		}).Error
+	return err
+}
+
+// 根据任务名称查询最近的执行记录，taskName 为空时查询全部任务
+func QueryTaskRunRecords(taskName string, limit int) ([]YYMTaskRunRecord, error) {
+	var records []YYMTaskRunRecord
+	db := global.DB().Order("started_at desc").Limit(limit)
+	if taskName != "" {
+		db = db.Where("task_name = ?", taskName)
+	}
+	err := db.Find(&records).Error
+	return records, err
+}
@@ -0,0 +1,239 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/apache/rocketmq-client-go/v2"
+	"github.com/apache/rocketmq-client-go/v2/consumer"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"go-task-service/cmd/global"
+	"go.uber.org/zap"
+	"runtime/debug"
+	"sync"
+)
+
+// 未指定 WithMaxRetries 时的最大重投次数，与 broker 默认值一致
+const DefaultMaxRetries = 16
+
+// ErrPermanent 不可重试的错误，处理器返回被它包装的错误时消息直接进入死信处理
+var ErrPermanent = errors.New("不可重试的消息")
+
+// Permanent 把错误标记为不可重试
+func Permanent(err error) error {
+	return fmt.Errorf("%w: %w", ErrPermanent, err)
+}
+
+// HandlerOptions 消费处理器配置
+type HandlerOptions struct {
+	// Concurrency 该 topic 同时处理的最大消息数，0 表示只受消费者协程数限制
+	Concurrency int
+	// MaxRetries 最大重投次数，超过后交给死信处理并确认消费
+	MaxRetries int32
+}
+
+// HandlerOption 注册处理器时的可选配置
+type HandlerOption func(*HandlerOptions)
+
+// WithConcurrency 设置处理器的并发上限
+func WithConcurrency(n int) HandlerOption {
+	return func(o *HandlerOptions) {
+		o.Concurrency = n
+	}
+}
+
+// WithMaxRetries 设置最大重投次数
+func WithMaxRetries(n int32) HandlerOption {
+	return func(o *HandlerOptions) {
+		o.MaxRetries = n
+	}
+}
+
+// DeadLetterFunc 消息重试耗尽或不可重试时的处理函数，返回错误时消息稍后重投，避免丢失
+type DeadLetterFunc func(ctx context.Context, msg *primitive.MessageExt, err error) error
+
+var deadLetterFunc DeadLetterFunc = storeConsumerDeadLetter
+
+// SetDeadLetterHandler 替换默认的死信处理（默认写入 MongoDB 死信集合）
+func SetDeadLetterHandler(fn DeadLetterFunc) {
+	deadLetterFunc = fn
+}
+
+// subscription 一个 topic 的订阅
+type subscription struct {
+	topic   string
+	tagExpr string
+	options HandlerOptions
+	sem     chan struct{}
+	// handle 解码并处理一条消息
+	handle func(ctx context.Context, msg *primitive.MessageExt) error
+}
+
+var (
+	subsMu        sync.Mutex
+	subscriptions []*subscription
+	subscribed    = make(map[string]bool)
+	// inflight 正在处理的消息，退出时等待
+	inflight sync.WaitGroup
+)
+
+// Handle 注册 topic 处理器，tagExpr 为 tag 表达式（如 "*"、"a || b"），
+// 消息体按 JSON 解码为 T 后交给 fn。同一个 topic 只能注册一次
+func Handle[T any](topic, tagExpr string, fn func(ctx context.Context, payload *T, msg *primitive.MessageExt) error, opts ...HandlerOption) error {
+	options := HandlerOptions{MaxRetries: DefaultMaxRetries}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	sub := &subscription{
+		topic:   topic,
+		tagExpr: tagExpr,
+		options: options,
+		handle: func(ctx context.Context, msg *primitive.MessageExt) error {
+			var payload T
+			if err := json.Unmarshal(msg.Body, &payload); err != nil {
+				// 消息体格式错误，重试也无法成功
+				return Permanent(fmt.Errorf("解析消息体失败: %w", err))
+			}
+			return fn(ctx, &payload, msg)
+		},
+	}
+	if options.Concurrency > 0 {
+		sub.sem = make(chan struct{}, options.Concurrency)
+	}
+
+	subsMu.Lock()
+	defer subsMu.Unlock()
+	if subscribed[topic] {
+		return fmt.Errorf("topic %s 已注册处理器", topic)
+	}
+	subscribed[topic] = true
+	subscriptions = append(subscriptions, sub)
+	return nil
+}
+
+// MustHandle 注册处理器，失败直接 panic，供处理器所在包的 init 使用
+func MustHandle[T any](topic, tagExpr string, fn func(ctx context.Context, payload *T, msg *primitive.MessageExt) error, opts ...HandlerOption) {
+	if err := Handle(topic, tagExpr, fn, opts...); err != nil {
+		panic("注册消息处理器失败: " + err.Error())
+	}
+}
+
+// StartConsumer 订阅所有已注册的 topic 并启动 global.RocketMQConsumer()，没有处理器时不启动
+func StartConsumer() error {
+	return SubscribeAndStart(global.RocketMQConsumer())
+}
+
+// SubscribeAndStart 在 c 上订阅所有已注册的 topic 并启动，没有处理器时不启动。
+// 配置变更重建消费者时用它在新消费者上重新订阅
+func SubscribeAndStart(c rocketmq.PushConsumer) error {
+	subsMu.Lock()
+	defer subsMu.Unlock()
+	if len(subscriptions) == 0 {
+		zap.L().Info("没有注册消息处理器，不启动rocketmq消费者")
+		return nil
+	}
+	if c == nil {
+		return errors.New("rocketmq消费者未初始化")
+	}
+	for _, sub := range subscriptions {
+		selector := consumer.MessageSelector{Type: consumer.TAG, Expression: sub.tagExpr}
+		if err := c.Subscribe(sub.topic, selector, sub.consume); err != nil {
+			return fmt.Errorf("订阅 topic %s 失败: %w", sub.topic, err)
+		}
+		zap.L().Info("订阅 topic 成功", zap.String("topic", sub.topic), zap.String("tags", sub.tagExpr))
+	}
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("启动rocketmq消费者失败: %w", err)
+	}
+	zap.L().Info("rocketmq消费者启动成功")
+	return nil
+}
+
+// ReplaceClients 用新的生产者和消费者替换全局实例，新实例需已启动（消费者已订阅）。
+// 旧实例通过 global.RetireClient 在替换前开始的任务和消息处理结束后关闭，
+// 关闭前旧消费者继续与新消费者一起消费，关闭后由 rebalance 把队列分给新消费者
+func ReplaceClients(p rocketmq.Producer, c rocketmq.PushConsumer) {
+	oldProducer := global.SetRocketMQProducer(p)
+	oldConsumer := global.SetRocketMQConsumer(c)
+	if oldConsumer != nil {
+		global.RetireClient("rocketmq-consumer", oldConsumer.Shutdown)
+	}
+	if oldProducer != nil {
+		global.RetireClient("rocketmq-producer", oldProducer.Shutdown)
+	}
+}
+
+// ShutdownConsumer 停止拉取新消息并等待正在处理的消息完成，ctx 到期后直接返回
+func ShutdownConsumer(ctx context.Context) error {
+	c := global.RocketMQConsumer()
+	if c == nil {
+		return nil
+	}
+	if err := c.Shutdown(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// consume rocketmq 回调，逐条处理，任一条需要重试时整批稍后重投
+func (s *subscription) consume(ctx context.Context, msgs ...*primitive.MessageExt) (consumer.ConsumeResult, error) {
+	defer global.UseClients()()
+	for _, msg := range msgs {
+		err := s.process(ctx, msg)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, ErrPermanent) || msg.ReconsumeTimes >= s.options.MaxRetries {
+			if dlqErr := deadLetterFunc(ctx, msg, err); dlqErr != nil {
+				zap.L().Error("保存死信失败，稍后重投",
+					zap.String("topic", msg.Topic),
+					zap.String("msg_id", msg.MsgId),
+					zap.NamedError("cause", err),
+					zap.Error(dlqErr),
+				)
+				return consumer.ConsumeRetryLater, dlqErr
+			}
+			continue
+		}
+		zap.L().Warn("消息处理失败，稍后重投",
+			zap.String("topic", msg.Topic),
+			zap.String("msg_id", msg.MsgId),
+			zap.Int32("reconsume_times", msg.ReconsumeTimes),
+			zap.Error(err),
+		)
+		return consumer.ConsumeRetryLater, err
+	}
+	return consumer.ConsumeSuccess, nil
+}
+
+// process 在并发限制内处理一条消息，捕获处理器 panic
+func (s *subscription) process(ctx context.Context, msg *primitive.MessageExt) (err error) {
+	if s.sem != nil {
+		s.sem <- struct{}{}
+		defer func() { <-s.sem }()
+	}
+	inflight.Add(1)
+	defer inflight.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			zap.L().Error("消息处理器发生 panic",
+				zap.String("topic", msg.Topic),
+				zap.Any("panic", r),
+				zap.String("stack", string(debug.Stack())),
+			)
+			err = fmt.Errorf("消息处理器 panic: %v", r)
+		}
+	}()
+	return s.handle(ctx, msg)
+}
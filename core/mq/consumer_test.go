@@ -0,0 +1,65 @@
+package mq
+
+import (
+	"github.com/apache/rocketmq-client-go/v2"
+	"go-task-service/cmd/global"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeProducer 只记录是否已关闭，其余方法不会被调用
+type fakeProducer struct {
+	rocketmq.Producer
+	shutdown atomic.Bool
+}
+
+func (p *fakeProducer) Shutdown() error {
+	p.shutdown.Store(true)
+	return nil
+}
+
+type fakeConsumer struct {
+	rocketmq.PushConsumer
+	shutdown atomic.Bool
+}
+
+func (c *fakeConsumer) Shutdown() error {
+	c.shutdown.Store(true)
+	return nil
+}
+
+func TestReplaceClientsDrainsOldClients(t *testing.T) {
+	oldP, oldC := &fakeProducer{}, &fakeConsumer{}
+	newP, newC := &fakeProducer{}, &fakeConsumer{}
+	prevP := global.SetRocketMQProducer(oldP)
+	prevC := global.SetRocketMQConsumer(oldC)
+	t.Cleanup(func() {
+		global.SetRocketMQProducer(prevP)
+		global.SetRocketMQConsumer(prevC)
+	})
+
+	// 替换前开始的工作单元，结束前旧实例不能关闭
+	done := global.UseClients()
+	ReplaceClients(newP, newC)
+
+	if global.RocketMQProducer() != newP || global.RocketMQConsumer() != newC {
+		t.Fatal("ReplaceClients() did not swap the global clients")
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if oldP.shutdown.Load() || oldC.shutdown.Load() {
+		t.Fatal("old clients shut down while a work unit was still using them")
+	}
+
+	done()
+	deadline := time.Now().Add(3 * time.Second)
+	for !oldP.shutdown.Load() || !oldC.shutdown.Load() {
+		if time.Now().After(deadline) {
+			t.Fatalf("old clients not shut down after drain: producer %v, consumer %v", oldP.shutdown.Load(), oldC.shutdown.Load())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if newP.shutdown.Load() || newC.shutdown.Load() {
+		t.Error("new clients shut down")
+	}
+}
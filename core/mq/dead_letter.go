@@ -0,0 +1,342 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"go-task-service/cmd/appconf"
+	"go-task-service/cmd/global"
+	"go-task-service/core/repository/mongodb_methods"
+	"go.mongodb.org/mongo-driver/bson"
+	bsonprimitive "go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// 重新投递的消息带上死信 ID，便于消费端排查
+const DeadLetterReplayProperty = "DEAD_LETTER_ID"
+
+var (
+	ErrDeadLetterNotFound = errors.New("死信消息不存在")
+	// ErrDeadLetterState 只有待处理状态的死信可以重新投递或丢弃
+	ErrDeadLetterState = errors.New("死信消息不是待处理状态")
+)
+
+// DeadLetterFilter 批量查询、重新投递、丢弃时的过滤条件，零值字段不参与过滤
+type DeadLetterFilter struct {
+	IDs    []string
+	Topic  string
+	Source string
+	Status string
+	Since  *time.Time
+	Until  *time.Time
+}
+
+// BulkReplayResult 批量重新投递的结果
+type BulkReplayResult struct {
+	Replayed int               `json:"replayed"`
+	Failed   int               `json:"failed"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+func deadLetterRepo() (*mongodb_methods.MongoRepo[mongodb_methods.YYMDeadLetter], error) {
+	db := global.MongoDB()
+	if db == nil {
+		return nil, errors.New("MongoDB未初始化")
+	}
+	return &mongodb_methods.MongoRepo[mongodb_methods.YYMDeadLetter]{
+		Collection: db.Collection(mongodb_methods.DeadLetterCollection),
+	}, nil
+}
+
+// SaveDeadLetter 把重试耗尽的消息写入死信集合，msgID 为 broker 消息 ID（发送失败时为空）
+func SaveDeadLetter(ctx context.Context, source string, msg *primitive.Message, msgID string, cause error, attempts int) error {
+	return saveDeadLetter(ctx, newDeadLetter(source, msg, msgID, cause, attempts))
+}
+
+func newDeadLetter(source string, msg *primitive.Message, msgID string, cause error, attempts int) mongodb_methods.YYMDeadLetter {
+	now := time.Now()
+	doc := mongodb_methods.YYMDeadLetter{
+		Source:     source,
+		Topic:      msg.Topic,
+		Tags:       msg.GetTags(),
+		Keys:       msg.GetKeys(),
+		MsgID:      msgID,
+		Body:       string(msg.Body),
+		Properties: msg.GetProperties(),
+		Attempts:   attempts,
+		Status:     mongodb_methods.DeadLetterStatusPending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if cause != nil {
+		doc.Error = cause.Error()
+	}
+	return doc
+}
+
+func saveDeadLetter(ctx context.Context, doc mongodb_methods.YYMDeadLetter) error {
+	repo, err := deadLetterRepo()
+	if err != nil {
+		return err
+	}
+	id, err := repo.Insert(ctx, doc)
+	if err != nil {
+		return fmt.Errorf("写入死信失败: %w", err)
+	}
+	zap.L().Warn("消息已写入死信",
+		zap.String("id", id.Hex()),
+		zap.String("source", doc.Source),
+		zap.String("topic", doc.Topic),
+		zap.String("keys", doc.Keys),
+		zap.Int("attempts", doc.Attempts),
+		zap.String("error", doc.Error),
+	)
+	return nil
+}
+
+// storeConsumerDeadLetter 消费端默认的死信处理，记录消费者组，重新投递时只投给该组
+func storeConsumerDeadLetter(ctx context.Context, msg *primitive.MessageExt, err error) error {
+	doc := newDeadLetter(mongodb_methods.DeadLetterSourceConsumer, &msg.Message, msg.MsgId, err, int(msg.ReconsumeTimes)+1)
+	doc.ConsumerGroup = ConsumerGroupName(global.Config())
+	return saveDeadLetter(ctx, doc)
+}
+
+// 消费者组重试 topic 的前缀，与 rocketmq-client-go 内部的 RetryGroupTopicPrefix 一致
+const retryGroupTopicPrefix = "%RETRY%"
+
+// ConsumerGroupName 返回消费者组在 broker 中的完整名称，配置了命名空间时带上命名空间前缀
+func ConsumerGroupName(conf *appconf.AppConfigMaster) string {
+	if conf.ROCKETMQNAMESPACE == "" || conf.ROCKETMQCONSUMERGROUP == "" {
+		return conf.ROCKETMQCONSUMERGROUP
+	}
+	return conf.ROCKETMQNAMESPACE + "%" + conf.ROCKETMQCONSUMERGROUP
+}
+
+// toBson 把过滤条件转换为 MongoDB 查询
+func (f DeadLetterFilter) toBson() (bson.M, error) {
+	filter := bson.M{}
+	if len(f.IDs) > 0 {
+		ids := make([]bsonprimitive.ObjectID, 0, len(f.IDs))
+		for _, v := range f.IDs {
+			id, err := bsonprimitive.ObjectIDFromHex(v)
+			if err != nil {
+				return nil, fmt.Errorf("死信 ID 格式错误: %s", v)
+			}
+			ids = append(ids, id)
+		}
+		filter["_id"] = bson.M{"$in": ids}
+	}
+	if f.Topic != "" {
+		filter["topic"] = f.Topic
+	}
+	if f.Source != "" {
+		filter["source"] = f.Source
+	}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+	created := bson.M{}
+	if f.Since != nil {
+		created["$gte"] = *f.Since
+	}
+	if f.Until != nil {
+		created["$lt"] = *f.Until
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+	return filter, nil
+}
+
+// ListDeadLetters 按创建时间倒序分页查询死信，同时返回符合条件的总数
+func ListDeadLetters(ctx context.Context, f DeadLetterFilter, offset, limit int64) ([]mongodb_methods.YYMDeadLetter, int64, error) {
+	repo, err := deadLetterRepo()
+	if err != nil {
+		return nil, 0, err
+	}
+	filter, err := f.toBson()
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := repo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetSkip(offset).SetLimit(limit)
+	docs, err := repo.Find(ctx, filter, opts)
+	return docs, total, err
+}
+
+// GetDeadLetter 根据 ID 查询一条死信
+func GetDeadLetter(ctx context.Context, id string) (*mongodb_methods.YYMDeadLetter, error) {
+	repo, err := deadLetterRepo()
+	if err != nil {
+		return nil, err
+	}
+	objectID, err := bsonprimitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrDeadLetterNotFound
+	}
+	doc, err := repo.FindOne(ctx, bson.M{"_id": objectID})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrDeadLetterNotFound
+	}
+	return doc, err
+}
+
+// deadLetterStore 重新投递时对死信集合的操作，由 MongoRepo 实现
+type deadLetterStore interface {
+	UpdateMany(ctx context.Context, filter interface{}, update interface{}) (int64, error)
+	UpdateOne(ctx context.Context, filter interface{}, update interface{}) error
+}
+
+// ReplayDeadLetter 把一条待处理的死信重新投递，见 replayMessage。
+// 投递前先把状态改为已投递，避免并发请求重复投递，投递失败时再改回待处理
+func ReplayDeadLetter(ctx context.Context, id string) error {
+	repo, err := deadLetterRepo()
+	if err != nil {
+		return err
+	}
+	doc, err := GetDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+	return replayDeadLetter(ctx, repo, doc, func(ctx context.Context, msg *primitive.Message) error {
+		_, err := global.RocketMQProducer().SendSync(ctx, msg)
+		return err
+	})
+}
+
+func replayDeadLetter(ctx context.Context, store deadLetterStore, doc *mongodb_methods.YYMDeadLetter, send func(ctx context.Context, msg *primitive.Message) error) error {
+	id := doc.ID.Hex()
+	now := time.Now()
+	claimed, err := store.UpdateMany(ctx,
+		bson.M{"_id": doc.ID, "status": mongodb_methods.DeadLetterStatusPending},
+		bson.M{"status": mongodb_methods.DeadLetterStatusReplayed, "replayed_at": now, "updated_at": now},
+	)
+	if err != nil {
+		return err
+	}
+	if claimed == 0 {
+		return ErrDeadLetterState
+	}
+
+	msg := replayMessage(doc)
+	sendErr := send(ctx, msg)
+
+	update := bson.M{"replay_count": doc.ReplayCount + 1, "replay_error": "", "updated_at": time.Now()}
+	if sendErr != nil {
+		update["status"] = mongodb_methods.DeadLetterStatusPending
+		update["replayed_at"] = doc.ReplayedAt
+		update["replay_error"] = sendErr.Error()
+	}
+	if err := store.UpdateOne(ctx, bson.M{"_id": doc.ID}, update); err != nil {
+		zap.L().Error("回写死信投递结果失败", zap.String("id", id), zap.Error(err))
+	}
+	if sendErr != nil {
+		return fmt.Errorf("重新投递失败: %w", sendErr)
+	}
+	zap.L().Info("死信已重新投递", zap.String("id", id), zap.String("topic", msg.Topic), zap.String("keys", doc.Keys))
+	return nil
+}
+
+// replayMessage 按死信内容构造重新投递的消息。发送端死信按原 topic 投递；消费端死信投递到
+// 失败的消费者组的重试 topic（%RETRY%组名），并在 RETRY_TOPIC 属性中带上原 topic，
+// 消费者收到后还原为原 topic 交给对应的处理器，同一 topic 的其他消费者组不会重复收到
+func replayMessage(doc *mongodb_methods.YYMDeadLetter) *primitive.Message {
+	topic := doc.Topic
+	consumerSide := doc.Source == mongodb_methods.DeadLetterSourceConsumer
+	if consumerSide {
+		group := doc.ConsumerGroup
+		if group == "" {
+			// 记录消费者组之前写入的死信，按当前配置的消费者组投递
+			group = ConsumerGroupName(global.Config())
+		}
+		topic = retryGroupTopicPrefix + group
+	}
+	msg := primitive.NewMessage(topic, []byte(doc.Body))
+	if doc.Tags != "" {
+		msg.WithTag(doc.Tags)
+	}
+	if doc.Keys != "" {
+		msg.WithKeys(strings.Split(doc.Keys, primitive.PropertyKeySeparator))
+	}
+	if consumerSide {
+		msg.WithProperty(primitive.PropertyRetryTopic, doc.Topic)
+	}
+	msg.WithProperty(DeadLetterReplayProperty, doc.ID.Hex())
+	return msg
+}
+
+// DiscardDeadLetter 丢弃一条待处理的死信，保留文档用于审计
+func DiscardDeadLetter(ctx context.Context, id, reason string) error {
+	objectID, err := bsonprimitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrDeadLetterNotFound
+	}
+	n, err := DiscardDeadLetters(ctx, bson.M{"_id": objectID}, reason)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		if _, err := GetDeadLetter(ctx, id); err != nil {
+			return err
+		}
+		return ErrDeadLetterState
+	}
+	return nil
+}
+
+// DiscardDeadLettersByFilter 批量丢弃符合条件的待处理死信，返回丢弃的数量
+func DiscardDeadLettersByFilter(ctx context.Context, f DeadLetterFilter, reason string) (int64, error) {
+	filter, err := f.toBson()
+	if err != nil {
+		return 0, err
+	}
+	return DiscardDeadLetters(ctx, filter, reason)
+}
+
+// DiscardDeadLetters 把 filter 命中的待处理死信标记为已丢弃
+func DiscardDeadLetters(ctx context.Context, filter bson.M, reason string) (int64, error) {
+	repo, err := deadLetterRepo()
+	if err != nil {
+		return 0, err
+	}
+	// 只丢弃待处理的死信，已投递的不受影响
+	filter["status"] = mongodb_methods.DeadLetterStatusPending
+	now := time.Now()
+	return repo.UpdateMany(ctx, filter, bson.M{
+		"status":         mongodb_methods.DeadLetterStatusDiscarded,
+		"discarded_at":   now,
+		"discard_reason": reason,
+		"updated_at":     now,
+	})
+}
+
+// ReplayDeadLetters 批量重新投递符合条件的待处理死信，最多处理 limit 条，单条失败不影响其他
+func ReplayDeadLetters(ctx context.Context, f DeadLetterFilter, limit int64) (BulkReplayResult, error) {
+	result := BulkReplayResult{Errors: map[string]string{}}
+	f.Status = mongodb_methods.DeadLetterStatusPending
+	docs, _, err := ListDeadLetters(ctx, f, 0, limit)
+	if err != nil {
+		return result, err
+	}
+	for _, doc := range docs {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		id := doc.ID.Hex()
+		if err := ReplayDeadLetter(ctx, id); err != nil {
+			result.Failed++
+			result.Errors[id] = err.Error()
+			continue
+		}
+		result.Replayed++
+	}
+	return result, nil
+}
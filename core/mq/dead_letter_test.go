@@ -0,0 +1,156 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"go-task-service/cmd/appconf"
+	"go-task-service/cmd/global"
+	"go-task-service/core/repository/mongodb_methods"
+	"go.mongodb.org/mongo-driver/bson"
+	bsonprimitive "go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+// memoryDeadLetterStore 只保存一条死信状态的内存实现，按 status 条件模拟认领
+type memoryDeadLetterStore struct {
+	status  string
+	updates []bson.M
+}
+
+func (s *memoryDeadLetterStore) UpdateMany(ctx context.Context, filter interface{}, update interface{}) (int64, error) {
+	if want, ok := filter.(bson.M)["status"]; ok && want != s.status {
+		return 0, nil
+	}
+	s.apply(update.(bson.M))
+	return 1, nil
+}
+
+func (s *memoryDeadLetterStore) UpdateOne(ctx context.Context, filter interface{}, update interface{}) error {
+	s.apply(update.(bson.M))
+	return nil
+}
+
+func (s *memoryDeadLetterStore) apply(update bson.M) {
+	if status, ok := update["status"].(string); ok {
+		s.status = status
+	}
+	s.updates = append(s.updates, update)
+}
+
+func newTestDeadLetter(source string) *mongodb_methods.YYMDeadLetter {
+	return &mongodb_methods.YYMDeadLetter{
+		ID:     bsonprimitive.NewObjectID(),
+		Source: source,
+		Topic:  "inventory_fetched",
+		Tags:   "steam",
+		Keys:   "76561198000000000",
+		Body:   `{"steam_aid":1}`,
+		Status: mongodb_methods.DeadLetterStatusPending,
+	}
+}
+
+func TestReplayMessageTargets(t *testing.T) {
+	oldConf := global.Config()
+	t.Cleanup(func() { global.SetConfig(oldConf) })
+	global.SetConfig(&appconf.AppConfigMaster{ROCKETMQCONSUMERGROUP: "current-group", ROCKETMQNAMESPACE: "ns"})
+
+	producerSide := newTestDeadLetter(mongodb_methods.DeadLetterSourceProducer)
+	consumerSide := newTestDeadLetter(mongodb_methods.DeadLetterSourceConsumer)
+	consumerSide.ConsumerGroup = "ns%failed-group"
+	legacy := newTestDeadLetter(mongodb_methods.DeadLetterSourceConsumer)
+
+	tests := []struct {
+		name      string
+		doc       *mongodb_methods.YYMDeadLetter
+		wantTopic string
+		wantRetry string
+	}{
+		{"producer side uses original topic", producerSide, "inventory_fetched", ""},
+		{"consumer side uses group retry topic", consumerSide, "%RETRY%ns%failed-group", "inventory_fetched"},
+		{"consumer side without group uses current group", legacy, "%RETRY%ns%current-group", "inventory_fetched"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := replayMessage(tt.doc)
+			if msg.Topic != tt.wantTopic {
+				t.Errorf("topic = %q, want %q", msg.Topic, tt.wantTopic)
+			}
+			if got := msg.GetProperty(primitive.PropertyRetryTopic); got != tt.wantRetry {
+				t.Errorf("RETRY_TOPIC = %q, want %q", got, tt.wantRetry)
+			}
+			if got := msg.GetProperty(DeadLetterReplayProperty); got != tt.doc.ID.Hex() {
+				t.Errorf("%s = %q, want %q", DeadLetterReplayProperty, got, tt.doc.ID.Hex())
+			}
+			if msg.GetTags() != tt.doc.Tags || msg.GetKeys() != tt.doc.Keys || string(msg.Body) != tt.doc.Body {
+				t.Errorf("message = %s/%s/%s, want original tags, keys and body", msg.GetTags(), msg.GetKeys(), msg.Body)
+			}
+		})
+	}
+}
+
+func TestConsumerGroupName(t *testing.T) {
+	if got := ConsumerGroupName(&appconf.AppConfigMaster{ROCKETMQCONSUMERGROUP: "g"}); got != "g" {
+		t.Errorf("ConsumerGroupName() = %q, want g", got)
+	}
+	if got := ConsumerGroupName(&appconf.AppConfigMaster{ROCKETMQCONSUMERGROUP: "g", ROCKETMQNAMESPACE: "ns"}); got != "ns%g" {
+		t.Errorf("ConsumerGroupName() = %q, want ns%%g", got)
+	}
+}
+
+func TestReplayDeadLetterSendFailureRollsBack(t *testing.T) {
+	doc := newTestDeadLetter(mongodb_methods.DeadLetterSourceConsumer)
+	doc.ConsumerGroup = "failed-group"
+	store := &memoryDeadLetterStore{status: mongodb_methods.DeadLetterStatusPending}
+	sendErr := errors.New("broker unavailable")
+
+	var statusWhileSending string
+	err := replayDeadLetter(context.Background(), store, doc, func(ctx context.Context, msg *primitive.Message) error {
+		statusWhileSending = store.status
+		return sendErr
+	})
+	if !errors.Is(err, sendErr) {
+		t.Fatalf("replayDeadLetter() error = %v, want %v", err, sendErr)
+	}
+	// 发送前已认领为已投递，失败后改回待处理，可以再次投递
+	if statusWhileSending != mongodb_methods.DeadLetterStatusReplayed {
+		t.Errorf("status while sending = %q, want %q", statusWhileSending, mongodb_methods.DeadLetterStatusReplayed)
+	}
+	if store.status != mongodb_methods.DeadLetterStatusPending {
+		t.Errorf("status after failure = %q, want %q", store.status, mongodb_methods.DeadLetterStatusPending)
+	}
+	last := store.updates[len(store.updates)-1]
+	if last["replay_error"] != sendErr.Error() || last["replay_count"] != 1 {
+		t.Errorf("result update = %v, want replay_error and replay_count recorded", last)
+	}
+
+	sent := 0
+	if err := replayDeadLetter(context.Background(), store, doc, func(ctx context.Context, msg *primitive.Message) error {
+		sent++
+		return nil
+	}); err != nil {
+		t.Fatalf("retry replayDeadLetter() error = %v", err)
+	}
+	if sent != 1 || store.status != mongodb_methods.DeadLetterStatusReplayed {
+		t.Errorf("retry sent %d, status %q, want 1 and %q", sent, store.status, mongodb_methods.DeadLetterStatusReplayed)
+	}
+}
+
+func TestReplayDeadLetterRequiresPending(t *testing.T) {
+	for _, status := range []string{mongodb_methods.DeadLetterStatusReplayed, mongodb_methods.DeadLetterStatusDiscarded} {
+		t.Run(status, func(t *testing.T) {
+			store := &memoryDeadLetterStore{status: status}
+			sent := false
+			err := replayDeadLetter(context.Background(), store, newTestDeadLetter(mongodb_methods.DeadLetterSourceProducer), func(ctx context.Context, msg *primitive.Message) error {
+				sent = true
+				return nil
+			})
+			if !errors.Is(err, ErrDeadLetterState) || sent {
+				t.Errorf("replayDeadLetter() error = %v, sent = %v, want ErrDeadLetterState without sending", err, sent)
+			}
+			if store.status != status {
+				t.Errorf("status = %q, want unchanged %q", store.status, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"go-task-service/cmd/global"
+	"go-task-service/core/tools"
+	"strconv"
+	"time"
+)
+
+// 库存刷新请求的 topic
+const InventoryDescTopic = "inventory_desc"
+
+// 爬虫抓取完 steam 库存后回传结果的 topic
+const InventoryFetchedTopic = "inventory_fetched"
+
+// 库存刷新消息的 schema 版本，字段发生不兼容变更时递增
+const InventoryRefreshVersion = 1
+
+// 刷新原因，同时作为 rocketmq 的 tag，消费者可按 tag 过滤
+const (
+	ReasonScheduled = "scheduled"
+	ReasonManual    = "manual"
+	ReasonRetry     = "retry"
+)
+
+// 刷新优先级，数值越大越优先
+const (
+	PriorityLow    = 1
+	PriorityNormal = 5
+	PriorityHigh   = 9
+)
+
+// TraceContext 链路追踪信息
+type TraceContext struct {
+	TraceID string `json:"trace_id"`
+}
+
+// InventoryRefreshMessage inventory_desc topic 的消息体
+type InventoryRefreshMessage struct {
+	Version     int          `json:"version"`
+	MessageID   string       `json:"message_id"`
+	SteamAID    int64        `json:"steam_aid"`
+	RequestedAt time.Time    `json:"requested_at"`
+	Reason      string       `json:"reason"`
+	Priority    int          `json:"priority"`
+	Trace       TraceContext `json:"trace"`
+}
+
+// NewInventoryRefreshMessage 构造一条库存刷新消息，消息 ID 由 snowflake 生成，追踪 ID 取自 ctx
+func NewInventoryRefreshMessage(ctx context.Context, steamAID int64, reason string, priority int) (*InventoryRefreshMessage, error) {
+	if global.SnowflakeNode == nil {
+		return nil, errors.New("snowflake 节点未初始化")
+	}
+	return &InventoryRefreshMessage{
+		Version:     InventoryRefreshVersion,
+		MessageID:   global.SnowflakeNode.Generate().String(),
+		SteamAID:    steamAID,
+		RequestedAt: time.Now(),
+		Reason:      reason,
+		Priority:    priority,
+		Trace:       TraceContext{TraceID: tools.TraceIDFromContext(ctx)},
+	}, nil
+}
+
+// ToMessage 转换为 rocketmq 消息，keys 为 steam_aid 和消息 ID，tag 为刷新原因
+func (m *InventoryRefreshMessage) ToMessage() (*primitive.Message, error) {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("序列化库存刷新消息失败: %w", err)
+	}
+	msg := primitive.NewMessage(InventoryDescTopic, body)
+	msg.WithKeys([]string{strconv.FormatInt(m.SteamAID, 10), m.MessageID})
+	msg.WithTag(m.Reason)
+	return msg, nil
+}
+
+// InventoryFetchedMessage inventory_fetched topic 的消息体，Inventory 为 steam 返回的原始库存 JSON，
+// 结构与 tools.Inventory 一致
+type InventoryFetchedMessage struct {
+	Version   int             `json:"version"`
+	MessageID string          `json:"message_id"`
+	SteamAID  int64           `json:"steam_aid"`
+	FetchedAt time.Time       `json:"fetched_at"`
+	Inventory json.RawMessage `json:"inventory"`
+	// Error 抓取失败时的错误信息，非空时 Inventory 不可用
+	Error string       `json:"error,omitempty"`
+	Trace TraceContext `json:"trace"`
+}
@@ -0,0 +1,256 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"go-task-service/cmd/global"
+	"go-task-service/core/curd_methods"
+	"go-task-service/core/repository/mongodb_methods"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// 发件箱投递参数
+const (
+	outboxPollInterval = time.Second
+	outboxBatchSize    = 100
+	outboxMaxAttempts  = 10
+	outboxBaseBackoff  = 5 * time.Second
+	outboxMaxBackoff   = 10 * time.Minute
+	// outboxClaimLease 认领后的租约，需覆盖一批消息逐条发送的时间，快到期时停止发送本批剩余消息
+	outboxClaimLease = 5 * time.Minute
+)
+
+// EnqueueOutbox 在 curd_methods.ExecuteTransaction 的事务中写入待发送消息，
+// 事务提交后由投递协程发送，保证消息与数据库变更一致（至少一次）
+func EnqueueOutbox(tx *gorm.DB, msg *primitive.Message) error {
+	_, err := curd_methods.AddOutboxMessage(curd_methods.YYMOutboxMessage{
+		Topic: msg.Topic,
+		Tags:  msg.GetTags(),
+		Keys:  msg.GetKeys(),
+		Body:  msg.Body,
+	}, tx)
+	return err
+}
+
+// outboxRelay 发件箱投递协程
+type outboxRelay struct {
+	// ctx 发送消息使用的 context，退出超时后取消，中断正在进行的发送
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+var relay *outboxRelay
+
+// StartOutboxRelay 启动发件箱投递协程
+func StartOutboxRelay() error {
+	if err := curd_methods.AutoMigrateOutboxMessage(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	relay = &outboxRelay{
+		ctx:    ctx,
+		cancel: cancel,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go relay.run()
+	zap.L().Info("发件箱投递协程已启动")
+	return nil
+}
+
+// StopOutboxRelay 停止投递协程。当前批次发送完正在发送的一条后停止，剩余消息释放认领留给其他实例；
+// ctx 到期时取消正在进行的发送，之后最多再等待一次发送超时和状态更新
+func StopOutboxRelay(ctx context.Context) error {
+	if relay == nil {
+		return nil
+	}
+	close(relay.stop)
+	defer relay.cancel()
+	select {
+	case <-relay.done:
+		zap.L().Info("发件箱投递协程已停止")
+		return nil
+	case <-ctx.Done():
+		relay.cancel()
+		<-relay.done
+		zap.L().Warn("等待发件箱投递超时，已中断正在进行的发送")
+		return ctx.Err()
+	}
+}
+
+func (r *outboxRelay) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			// 一批处理满时说明还有积压，立即处理下一批
+			for {
+				n, err := relayOutboxBatch(r.ctx, r.stop)
+				if err != nil {
+					zap.L().Error("发件箱投递失败", zap.Error(err))
+					break
+				}
+				if n < outboxBatchSize {
+					break
+				}
+				select {
+				case <-r.stop:
+					return
+				default:
+				}
+			}
+		}
+	}
+}
+
+// relayOutboxBatch 投递一批到期消息，返回本批处理条数。认领在一个短事务中完成，
+// 发送和状态更新都在事务外逐条进行，避免发送期间长时间持有行锁。stop 关闭或 ctx 取消时
+// 不再发送剩余消息，释放认领后返回
+func relayOutboxBatch(ctx context.Context, stop <-chan struct{}) (int, error) {
+	defer global.UseClients()()
+	token := newClaimToken()
+	var messages []curd_methods.YYMOutboxMessage
+	err := curd_methods.ExecuteTransaction(ctx, func(tx *gorm.DB) error {
+		var err error
+		messages, err = curd_methods.ClaimPendingOutboxMessages(ctx, outboxBatchSize, token, outboxClaimLease, tx)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	// 留出余量，避免租约到期后其他实例重新认领时本实例仍在发送
+	deadline := time.Now().Add(outboxClaimLease * 4 / 5)
+	for i, m := range messages {
+		if time.Now().After(deadline) {
+			zap.L().Warn("发件箱认领租约即将到期，剩余消息等待重新认领", zap.Int("remaining", len(messages)-i))
+			break
+		}
+		if relayStopped(ctx, stop) {
+			break
+		}
+		if err := relayOutboxMessage(ctx, m, token); err != nil {
+			// 单条更新失败不影响同批其他消息，租约到期后会重新认领
+			zap.L().Error("更新发件箱消息状态失败", zap.Int64("id", m.ID), zap.Error(err))
+		}
+	}
+	if relayStopped(ctx, stop) {
+		releaseOutboxClaim(token)
+	}
+	return len(messages), nil
+}
+
+// relayStopped 投递协程是否正在退出
+func relayStopped(ctx context.Context, stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// releaseOutboxClaim 退出时释放本批剩余消息的认领，其他实例无需等待租约到期即可继续发送，
+// 释放失败时等待租约到期
+func releaseOutboxClaim(token string) {
+	released, err := curd_methods.ReleaseOutboxClaim(token, global.DB())
+	if err != nil {
+		zap.L().Error("释放发件箱消息认领失败，等待租约到期后重新认领", zap.Error(err))
+		return
+	}
+	if released > 0 {
+		zap.L().Info("投递协程退出，已释放剩余消息的认领", zap.Int64("released", released))
+	}
+}
+
+// relayOutboxMessage 发送一条已认领的发件箱消息并更新状态，只有数据库更新失败才返回错误
+func relayOutboxMessage(ctx context.Context, m curd_methods.YYMOutboxMessage, token string) error {
+	_, sendErr := global.RocketMQProducer().SendSync(ctx, outboxToMessage(m))
+	if sendErr != nil && ctx.Err() != nil {
+		// 退出时被取消的发送不计入重试次数，由 releaseOutboxClaim 释放认领
+		return nil
+	}
+	if sendErr == nil {
+		ok, err := curd_methods.MarkOutboxMessageSent(m.ID, token, global.DB())
+		if err == nil && !ok {
+			zap.L().Warn("发件箱消息的认领已过期，可能被重复发送", zap.Int64("id", m.ID))
+		}
+		return err
+	}
+
+	attempts := m.Attempts + 1
+	status := curd_methods.OutboxStatusPending
+	if attempts >= outboxMaxAttempts {
+		status = curd_methods.OutboxStatusFailed
+	}
+	ok, err := curd_methods.MarkOutboxMessageFailed(m.ID, token, status, sendErr.Error(), time.Now().Add(outboxBackoff(attempts)), global.DB())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		zap.L().Warn("发件箱消息的认领已过期，由新的认领继续处理", zap.Int64("id", m.ID), zap.Error(sendErr))
+		return nil
+	}
+	if status == curd_methods.OutboxStatusPending {
+		zap.L().Warn("发件箱消息发送失败，等待重试",
+			zap.Int64("id", m.ID),
+			zap.String("topic", m.Topic),
+			zap.Int("attempts", attempts),
+			zap.Error(sendErr),
+		)
+		return nil
+	}
+	zap.L().Error("发件箱消息超过最大重试次数，放弃发送",
+		zap.Int64("id", m.ID),
+		zap.String("topic", m.Topic),
+		zap.Int("attempts", attempts),
+		zap.Error(sendErr),
+	)
+	// 发件箱记录本身保留失败状态，写入死信只为统一排查和重新投递
+	if err := SaveDeadLetter(ctx, mongodb_methods.DeadLetterSourceProducer, outboxToMessage(m), "", sendErr, attempts); err != nil {
+		zap.L().Error("发件箱消息写入死信失败", zap.Int64("id", m.ID), zap.Error(err))
+	}
+	return nil
+}
+
+// newClaimToken 生成一次认领的 token，区分不同实例和批次
+func newClaimToken() string {
+	if global.SnowflakeNode != nil {
+		return "outbox-" + global.SnowflakeNode.Generate().String()
+	}
+	return fmt.Sprintf("outbox-%d", time.Now().UnixNano())
+}
+
+// outboxBackoff 第 attempts 次失败后的等待时间
+func outboxBackoff(attempts int) time.Duration {
+	d := outboxBaseBackoff
+	for i := 1; i < attempts && d < outboxMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > outboxMaxBackoff {
+		d = outboxMaxBackoff
+	}
+	return d
+}
+
+func outboxToMessage(m curd_methods.YYMOutboxMessage) *primitive.Message {
+	msg := primitive.NewMessage(m.Topic, m.Body)
+	if m.Tags != "" {
+		msg.WithTag(m.Tags)
+	}
+	if m.Keys != "" {
+		msg.WithKeys(strings.Split(m.Keys, primitive.PropertyKeySeparator))
+	}
+	return msg
+}
@@ -0,0 +1,153 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"go-task-service/cmd/global"
+	"go.uber.org/zap"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// 发送器默认参数
+const (
+	DefaultPublishConcurrency  = 16
+	DefaultPublishMaxAttempts  = 3
+	DefaultPublishRetryBackoff = 500 * time.Millisecond
+)
+
+// PublishOptions 批量异步发送的参数，零值字段使用默认值
+type PublishOptions struct {
+	// Concurrency 同时在途的消息数上限
+	Concurrency int
+	// MaxAttempts 单条消息最多发送次数（包含第一次）
+	MaxAttempts int
+	// RetryBackoff 重试间隔，每次重试翻倍
+	RetryBackoff time.Duration
+}
+
+// PublishFailure 最终发送失败的消息
+type PublishFailure struct {
+	Message  *primitive.Message
+	Err      error
+	Attempts int
+}
+
+// PublishSummary 批量发送结果汇总
+type PublishSummary struct {
+	Sent     int
+	Failed   int
+	Failures []PublishFailure
+}
+
+// Publisher 基于 SendAsync 的有界并发发送器，单条消息失败时按退避重试，
+// 不会因为一条失败而中断整批发送
+type Publisher struct {
+	opts PublishOptions
+	sem  chan struct{}
+	wg   sync.WaitGroup
+
+	mu      sync.Mutex
+	summary PublishSummary
+}
+
+// NewPublisher 创建发送器
+func NewPublisher(opts PublishOptions) *Publisher {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultPublishConcurrency
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultPublishMaxAttempts
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = DefaultPublishRetryBackoff
+	}
+	return &Publisher{
+		opts: opts,
+		sem:  make(chan struct{}, opts.Concurrency),
+	}
+}
+
+// Publish 异步发送一条消息，在途消息达到上限时阻塞等待。
+// onDone 在消息最终成功或放弃重试后调用，err 为最后一次发送的错误，attempts 为发送次数
+func (p *Publisher) Publish(ctx context.Context, msg *primitive.Message, onDone func(err error, attempts int)) error {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	p.wg.Add(1)
+	p.send(ctx, msg, 1, onDone)
+	return nil
+}
+
+// send 发送一次，失败且未超过次数上限时延迟重发
+func (p *Publisher) send(ctx context.Context, msg *primitive.Message, attempt int, onDone func(err error, attempts int)) {
+	err := global.RocketMQProducer().SendAsync(ctx, func(_ context.Context, result *primitive.SendResult, err error) {
+		if err == nil && result != nil && result.Status != primitive.SendOK {
+			err = fmt.Errorf("发送状态异常: %d", result.Status)
+		}
+		p.handleResult(ctx, msg, attempt, err, onDone)
+	}, msg)
+	if err != nil {
+		// 同步返回的错误（生产者未启动、消息校验失败等）同样按重试处理
+		p.handleResult(ctx, msg, attempt, err, onDone)
+	}
+}
+
+func (p *Publisher) handleResult(ctx context.Context, msg *primitive.Message, attempt int, err error, onDone func(err error, attempts int)) {
+	if err != nil && attempt < p.opts.MaxAttempts && ctx.Err() == nil {
+		backoff := p.opts.RetryBackoff << (attempt - 1)
+		zap.L().Warn("消息发送失败，等待重试",
+			zap.String("topic", msg.Topic),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		time.AfterFunc(backoff, func() {
+			p.send(ctx, msg, attempt+1, onDone)
+		})
+		return
+	}
+
+	p.mu.Lock()
+	if err != nil {
+		p.summary.Failed++
+		p.summary.Failures = append(p.summary.Failures, PublishFailure{Message: msg, Err: err, Attempts: attempt})
+	} else {
+		p.summary.Sent++
+	}
+	p.mu.Unlock()
+
+	runCallback(msg, onDone, err, attempt)
+	<-p.sem
+	p.wg.Done()
+}
+
+// runCallback 调用 onDone 并捕获 panic。回调运行在 rocketmq 的发送协程或重试定时器中，
+// 任务的 safeRun 捕获不到，panic 会导致进程退出，也会让 Wait 一直等不到在途消息结束
+func runCallback(msg *primitive.Message, onDone func(err error, attempts int), err error, attempt int) {
+	if onDone == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			zap.L().Error("消息发送回调发生 panic",
+				zap.String("topic", msg.Topic),
+				zap.Any("panic", r),
+				zap.String("stack", string(debug.Stack())),
+			)
+		}
+	}()
+	onDone(err, attempt)
+}
+
+// Wait 等待所有在途消息完成并返回汇总结果
+func (p *Publisher) Wait() PublishSummary {
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.summary
+}
@@ -0,0 +1,45 @@
+package mongodb_methods
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// 死信消息集合
+const DeadLetterCollection = "yym_dead_letter"
+
+// 死信来源
+const (
+	DeadLetterSourceProducer = "producer" // 发送重试耗尽
+	DeadLetterSourceConsumer = "consumer" // 消费重试耗尽或不可重试
+)
+
+// 死信状态
+const (
+	DeadLetterStatusPending   = "pending"   // 待处理
+	DeadLetterStatusReplayed  = "replayed"  // 已重新投递
+	DeadLetterStatusDiscarded = "discarded" // 已丢弃
+)
+
+// YYMDeadLetter 重试耗尽的消息，保存原始内容以便排查和重新投递
+type YYMDeadLetter struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Source        string             `bson:"source" json:"source"`
+	Topic         string             `bson:"topic" json:"topic"`
+	Tags          string             `bson:"tags" json:"tags"`
+	Keys          string             `bson:"keys" json:"keys"`
+	MsgID         string             `bson:"msg_id" json:"msg_id"`                                     // 消费端的 broker 消息 ID，发送失败时为空
+	ConsumerGroup string             `bson:"consumer_group,omitempty" json:"consumer_group,omitempty"` // 消费端死信所属的消费者组（含命名空间），重新投递时只投给该组
+	Body          string             `bson:"body" json:"body"`
+	Properties    map[string]string  `bson:"properties" json:"properties"`
+	Error         string             `bson:"error" json:"error"`
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	Status        string             `bson:"status" json:"status"`
+	ReplayCount   int                `bson:"replay_count" json:"replay_count"`
+	ReplayError   string             `bson:"replay_error" json:"replay_error"` // 最近一次重新投递失败的原因
+	ReplayedAt    *time.Time         `bson:"replayed_at" json:"replayed_at"`
+	DiscardedAt   *time.Time         `bson:"discarded_at" json:"discarded_at"`
+	DiscardReason string             `bson:"discard_reason" json:"discard_reason"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
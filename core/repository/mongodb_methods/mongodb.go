@@ -5,12 +5,20 @@ import (
 	"go.mongodb.org/mongo-driver/bson"           // 用于构建 MongoDB 查询语法
 	"go.mongodb.org/mongo-driver/bson/primitive" // 提供 MongoDB 原生类型，如 ObjectID
 	"go.mongodb.org/mongo-driver/mongo"          // MongoDB 官方驱动
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
 )
 
+// steam 库存快照集合
+const SteamInventoryCollection = "yym_steam_inventory"
+
 type YYMSteamInventory struct {
 	ID            primitive.ObjectID `bson:"_id,omitempty"`
 	SteamAID      string             `bson:"steam_aid"`
-	InventoryDesc string             `bson:"inventory_desc"`
+	InventoryDesc string             `bson:"inventory_desc"` // 抓取到的原始库存 JSON
+	AssetCount    int                `bson:"asset_count"`
+	FetchedAt     time.Time          `bson:"fetched_at"`
+	UpdatedAt     time.Time          `bson:"updated_at"`
 }
 
 // MongoRepo 是一个通用的 MongoDB 仓库结构体，使用泛型 T 来支持任意文档类型
@@ -58,3 +66,46 @@ func (r *MongoRepo[T]) DeleteOne(ctx context.Context, filter interface{}) error
 	// 返回错误信息（如果有）
 	return err
 }
+
+// Upsert 根据 filter 条件更新一条文档，不存在时插入，使用 `$set` 更新字段
+func (r *MongoRepo[T]) Upsert(ctx context.Context, filter interface{}, update interface{}) error {
+	// 调用 UpdateOne 方法并开启 upsert
+	_, err := r.Collection.UpdateOne(ctx, filter, bson.M{"$set": update}, options.Update().SetUpsert(true))
+	// 返回错误信息（如果有）
+	return err
+}
+
+// EnsureIndexes 在集合上创建索引，已存在的相同索引不会重复创建
+func (r *MongoRepo[T]) EnsureIndexes(ctx context.Context, models ...mongo.IndexModel) error {
+	_, err := r.Collection.Indexes().CreateMany(ctx, models)
+	return err
+}
+
+// Find 根据 filter 条件查询多条文档，opts 可指定排序、分页等
+func (r *MongoRepo[T]) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) ([]T, error) {
+	// 执行查询，得到游标
+	cursor, err := r.Collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// 一次性解码全部结果，All 会关闭游标
+	results := make([]T, 0)
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Count 统计符合 filter 条件的文档数量
+func (r *MongoRepo[T]) Count(ctx context.Context, filter interface{}) (int64, error) {
+	return r.Collection.CountDocuments(ctx, filter)
+}
+
+// UpdateMany 根据 filter 条件更新多条文档，使用 `$set` 更新字段，返回修改的数量
+func (r *MongoRepo[T]) UpdateMany(ctx context.Context, filter interface{}, update interface{}) (int64, error) {
+	res, err := r.Collection.UpdateMany(ctx, filter, bson.M{"$set": update})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
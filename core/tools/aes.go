@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"go-task-service/cmd/global"
+	"os"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 // 直接用正则去掉所有 {e} 前缀
@@ -98,3 +104,177 @@ func DecryptAES(encrypted string, inType string) string {
 
 	return string(decryptedBytes)
 }
+
+// 配置项加密前缀，{e} 为旧的 AES-CBC 格式，{e2} 为带密钥版本的 AES-GCM 格式
+const (
+	EncryptionPrefixCBC = "{e}"
+	EncryptionPrefixGCM = "{e2}"
+)
+
+// 密钥环环境变量，APP_AES_KEYS 形如 "2:base64key,3:base64key"，APP_AES_KEY_VERSION 为加密使用的版本
+const (
+	aesKeysEnv       = "APP_AES_KEYS"
+	aesKeyVersionEnv = "APP_AES_KEY_VERSION"
+)
+
+// {e2} 只使用通过环境变量提供的密钥，内置的 global.AESKey 随代码公开，只用于解密旧的 {e} 配置。
+// 版本号 0 保留，表示尚未选择加密版本
+var (
+	aesKeysMu sync.RWMutex
+	// aesKeys 按版本保存的 GCM 密钥，解密时根据密文中的版本号选择密钥
+	aesKeys = map[byte][]byte{}
+	// aesKeyVersion 加密使用的密钥版本
+	aesKeyVersion byte
+)
+
+// RegisterAESKey 添加一个版本的密钥，版本号为 1-255，密钥长度需为 16/24/32 字节且不能是内置密钥
+func RegisterAESKey(version byte, key []byte) error {
+	if version == 0 {
+		return errors.New("密钥版本需在 1-255 之间")
+	}
+	if _, err := aes.NewCipher(key); err != nil {
+		return fmt.Errorf("密钥版本 %d 无效: %w", version, err)
+	}
+	if bytes.Equal(key, global.AESKey) {
+		return fmt.Errorf("密钥版本 %d 不能使用内置密钥", version)
+	}
+	aesKeysMu.Lock()
+	defer aesKeysMu.Unlock()
+	aesKeys[version] = key
+	return nil
+}
+
+// SetAESKeyVersion 设置加密使用的密钥版本，该版本必须已注册
+func SetAESKeyVersion(version byte) error {
+	aesKeysMu.Lock()
+	defer aesKeysMu.Unlock()
+	if _, ok := aesKeys[version]; !ok {
+		return fmt.Errorf("密钥版本 %d 未注册", version)
+	}
+	aesKeyVersion = version
+	return nil
+}
+
+// LoadAESKeysFromEnv 从环境变量加载密钥环，未设置 APP_AES_KEY_VERSION 时使用最大的版本加密。
+// 未设置 APP_AES_KEYS 时无法使用 {e2}，旧的 {e} 配置不受影响
+func LoadAESKeysFromEnv() error {
+	var latest byte
+	if value := os.Getenv(aesKeysEnv); value != "" {
+		for _, item := range strings.Split(value, ",") {
+			versionText, keyText, ok := strings.Cut(strings.TrimSpace(item), ":")
+			if !ok {
+				return fmt.Errorf("%s 格式错误，应为 版本:base64密钥", aesKeysEnv)
+			}
+			version, err := strconv.ParseUint(versionText, 10, 8)
+			if err != nil {
+				return fmt.Errorf("%s 中的密钥版本无效: %s", aesKeysEnv, versionText)
+			}
+			key, err := base64.StdEncoding.DecodeString(keyText)
+			if err != nil {
+				return fmt.Errorf("%s 中版本 %d 的密钥不是合法的 base64", aesKeysEnv, version)
+			}
+			if err := RegisterAESKey(byte(version), key); err != nil {
+				return err
+			}
+			latest = max(latest, byte(version))
+		}
+	}
+	if value := os.Getenv(aesKeyVersionEnv); value != "" {
+		version, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			return fmt.Errorf("%s 无效: %s", aesKeyVersionEnv, value)
+		}
+		return SetAESKeyVersion(byte(version))
+	}
+	if latest != 0 {
+		return SetAESKeyVersion(latest)
+	}
+	return nil
+}
+
+func lookupAESKey(version byte) ([]byte, bool) {
+	aesKeysMu.RLock()
+	defer aesKeysMu.RUnlock()
+	key, ok := aesKeys[version]
+	return key, ok
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptAESGCM 使用当前版本的密钥加密，返回 {e2} 前缀的密文：
+// base64(版本号 1 字节 + 随机 nonce + 密文和认证标签)，版本号同时作为附加认证数据。
+// 未配置密钥环时返回错误，不会退回到内置密钥
+func EncryptAESGCM(text string) (string, error) {
+	aesKeysMu.RLock()
+	version := aesKeyVersion
+	key, ok := aesKeys[version]
+	aesKeysMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("未配置 {e2} 加密密钥，请通过 %s 环境变量提供", aesKeysEnv)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	header := append([]byte{version}, nonce...)
+	sealed := gcm.Seal(header, nonce, []byte(text), []byte{version})
+	return EncryptionPrefixGCM + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptAESGCM 解密 EncryptAESGCM 生成的密文，{e2} 前缀可有可无，密文被篡改时返回错误
+func DecryptAESGCM(encrypted string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, EncryptionPrefixGCM))
+	if err != nil {
+		return "", fmt.Errorf("密文不是合法的 base64: %w", err)
+	}
+	if len(data) < 1 {
+		return "", errors.New("密文为空")
+	}
+	version := data[0]
+	key, ok := lookupAESKey(version)
+	if !ok {
+		return "", fmt.Errorf("密钥版本 %d 未注册", version)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < 1+gcm.NonceSize()+gcm.Overhead() {
+		return "", errors.New("密文长度不足")
+	}
+	nonce, sealed := data[1:1+gcm.NonceSize()], data[1+gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, sealed, []byte{version})
+	if err != nil {
+		return "", errors.New("密文校验失败，密钥错误或内容被篡改")
+	}
+	return string(plain), nil
+}
+
+// DecryptConfigValue 解密配置项，支持 {e2}（AES-GCM）和 {e}（旧的 AES-CBC），其他值原样返回
+func DecryptConfigValue(value string) (plain string, err error) {
+	switch {
+	case strings.HasPrefix(value, EncryptionPrefixGCM):
+		return DecryptAESGCM(value)
+	case strings.HasPrefix(value, EncryptionPrefixCBC):
+		// 旧格式解密失败时会 panic，这里转换为错误
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("解密 {e} 配置项失败: %v", r)
+			}
+		}()
+		return DecryptAES(RemoveEncryptionPrefix(value), "base64"), nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,212 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/base64"
+	"go-task-service/cmd/global"
+	"strings"
+	"testing"
+)
+
+// withAESKeys 用给定的密钥环替换全局密钥环，测试结束后恢复
+func withAESKeys(t *testing.T, version byte, keys map[byte][]byte) {
+	t.Helper()
+	aesKeysMu.Lock()
+	oldKeys, oldVersion := aesKeys, aesKeyVersion
+	aesKeys, aesKeyVersion = map[byte][]byte{}, 0
+	aesKeysMu.Unlock()
+	t.Cleanup(func() {
+		aesKeysMu.Lock()
+		aesKeys, aesKeyVersion = oldKeys, oldVersion
+		aesKeysMu.Unlock()
+	})
+	for v, key := range keys {
+		if err := RegisterAESKey(v, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if version != 0 {
+		if err := SetAESKeyVersion(version); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestAESGCMRoundTrip(t *testing.T) {
+	withAESKeys(t, 2, map[byte][]byte{2: testKey(2)})
+	for _, plain := range []string{"", "secret", "mongodb://user:p@ss@host:27017/db", strings.Repeat("长", 1000)} {
+		encrypted, err := EncryptAESGCM(plain)
+		if err != nil {
+			t.Fatalf("EncryptAESGCM(%q) error = %v", plain, err)
+		}
+		if !strings.HasPrefix(encrypted, EncryptionPrefixGCM) {
+			t.Fatalf("EncryptAESGCM(%q) = %q, missing %s prefix", plain, encrypted, EncryptionPrefixGCM)
+		}
+		got, err := DecryptAESGCM(encrypted)
+		if err != nil || got != plain {
+			t.Errorf("DecryptAESGCM() = %q, %v, want %q", got, err, plain)
+		}
+		got, err = DecryptConfigValue(encrypted)
+		if err != nil || got != plain {
+			t.Errorf("DecryptConfigValue() = %q, %v, want %q", got, err, plain)
+		}
+	}
+}
+
+func TestAESGCMKeyRotation(t *testing.T) {
+	withAESKeys(t, 2, map[byte][]byte{2: testKey(2), 3: testKey(3)})
+	old, err := EncryptAESGCM("rotated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetAESKeyVersion(3); err != nil {
+		t.Fatal(err)
+	}
+	// 切换加密版本后，旧版本的密文仍然可以解密
+	if got, err := DecryptAESGCM(old); err != nil || got != "rotated" {
+		t.Errorf("DecryptAESGCM(old) = %q, %v", got, err)
+	}
+	current, err := EncryptAESGCM("rotated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(current, EncryptionPrefixGCM))
+	if data[0] != 3 {
+		t.Errorf("encrypted with version %d, want 3", data[0])
+	}
+}
+
+func TestAESGCMRejectsTampering(t *testing.T) {
+	withAESKeys(t, 2, map[byte][]byte{2: testKey(2)})
+	encrypted, err := EncryptAESGCM("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, EncryptionPrefixGCM))
+
+	tests := []struct {
+		name   string
+		offset int
+	}{
+		{"nonce", 1},
+		{"ciphertext", 1 + 12},
+		{"tag", len(data) - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := append([]byte(nil), data...)
+			tampered[tt.offset] ^= 0x01
+			if _, err := DecryptAESGCM(base64.StdEncoding.EncodeToString(tampered)); err == nil {
+				t.Error("DecryptAESGCM() error = nil, want error")
+			}
+		})
+	}
+
+	// 版本号作为附加认证数据，改成另一个已注册的版本同样无法解密
+	withAESKeys(t, 2, map[byte][]byte{2: testKey(2), 3: testKey(2)})
+	tampered := append([]byte(nil), data...)
+	tampered[0] = 3
+	if _, err := DecryptAESGCM(base64.StdEncoding.EncodeToString(tampered)); err == nil {
+		t.Error("DecryptAESGCM() with swapped version error = nil, want error")
+	}
+}
+
+func TestAESGCMDecryptErrors(t *testing.T) {
+	withAESKeys(t, 2, map[byte][]byte{2: testKey(2)})
+	encrypted, err := EncryptAESGCM("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, EncryptionPrefixGCM))
+	unknown := append([]byte{9}, data[1:]...)
+
+	tests := []struct {
+		name, value, wantErr string
+	}{
+		{"unknown version", EncryptionPrefixGCM + base64.StdEncoding.EncodeToString(unknown), "未注册"},
+		{"truncated", EncryptionPrefixGCM + base64.StdEncoding.EncodeToString(data[:1+12+4]), "长度不足"},
+		{"version only", EncryptionPrefixGCM + base64.StdEncoding.EncodeToString(data[:1]), "长度不足"},
+		{"empty", EncryptionPrefixGCM, "为空"},
+		{"not base64", EncryptionPrefixGCM + "!!!", "base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecryptAESGCM(tt.value)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("DecryptAESGCM() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAESGCMRequiresConfiguredKey(t *testing.T) {
+	withAESKeys(t, 0, nil)
+	if _, err := EncryptAESGCM("secret"); err == nil {
+		t.Error("EncryptAESGCM() without keys error = nil, want error")
+	}
+	if err := RegisterAESKey(1, global.AESKey); err == nil {
+		t.Error("RegisterAESKey(built-in key) error = nil, want error")
+	}
+	if err := RegisterAESKey(0, testKey(1)); err == nil {
+		t.Error("RegisterAESKey(0) error = nil, want error")
+	}
+	if err := RegisterAESKey(1, []byte("short")); err == nil {
+		t.Error("RegisterAESKey(short key) error = nil, want error")
+	}
+}
+
+func TestLoadAESKeysFromEnv(t *testing.T) {
+	encode := base64.StdEncoding.EncodeToString
+	t.Run("defaults to latest version", func(t *testing.T) {
+		withAESKeys(t, 0, nil)
+		t.Setenv(aesKeysEnv, "2:"+encode(testKey(2))+", 5:"+encode(testKey(5)))
+		t.Setenv(aesKeyVersionEnv, "")
+		if err := LoadAESKeysFromEnv(); err != nil {
+			t.Fatal(err)
+		}
+		if aesKeyVersion != 5 {
+			t.Errorf("aesKeyVersion = %d, want 5", aesKeyVersion)
+		}
+	})
+	t.Run("explicit version", func(t *testing.T) {
+		withAESKeys(t, 0, nil)
+		t.Setenv(aesKeysEnv, "2:"+encode(testKey(2))+",5:"+encode(testKey(5)))
+		t.Setenv(aesKeyVersionEnv, "2")
+		if err := LoadAESKeysFromEnv(); err != nil {
+			t.Fatal(err)
+		}
+		if aesKeyVersion != 2 {
+			t.Errorf("aesKeyVersion = %d, want 2", aesKeyVersion)
+		}
+	})
+	t.Run("no keys", func(t *testing.T) {
+		withAESKeys(t, 0, nil)
+		t.Setenv(aesKeysEnv, "")
+		t.Setenv(aesKeyVersionEnv, "")
+		if err := LoadAESKeysFromEnv(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := EncryptAESGCM("secret"); err == nil {
+			t.Error("EncryptAESGCM() without APP_AES_KEYS error = nil, want error")
+		}
+	})
+	t.Run("built-in key rejected", func(t *testing.T) {
+		withAESKeys(t, 0, nil)
+		t.Setenv(aesKeysEnv, "1:"+encode(global.AESKey))
+		if err := LoadAESKeysFromEnv(); err == nil {
+			t.Error("LoadAESKeysFromEnv() error = nil, want error")
+		}
+	})
+	t.Run("unregistered version", func(t *testing.T) {
+		withAESKeys(t, 0, nil)
+		t.Setenv(aesKeysEnv, "2:"+encode(testKey(2)))
+		t.Setenv(aesKeyVersionEnv, "3")
+		if err := LoadAESKeysFromEnv(); err == nil {
+			t.Error("LoadAESKeysFromEnv() error = nil, want error")
+		}
+	})
+}
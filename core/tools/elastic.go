@@ -14,6 +14,24 @@ type InventoryResponse []Inventory
 type Inventory struct {
 	Assets       []Asset       `json:"assets"`
 	Descriptions []Description `json:"descriptions"`
+	// 以下字段为 steam 接口的分页和状态信息，未返回时为 nil
+	Success             *int `json:"success,omitempty"`
+	MoreItems           *int `json:"more_items,omitempty"`
+	TotalInventoryCount *int `json:"total_inventory_count,omitempty"`
+}
+
+// Complete 判断快照是否为完整的库存：steam 返回失败、还有下一页或条数少于总数时都不完整
+func (inv *Inventory) Complete() bool {
+	if inv.Success != nil && *inv.Success != 1 {
+		return false
+	}
+	if inv.MoreItems != nil && *inv.MoreItems != 0 {
+		return false
+	}
+	if inv.TotalInventoryCount != nil && len(inv.Assets) < *inv.TotalInventoryCount {
+		return false
+	}
+	return true
 }
 
 type Asset struct {
@@ -86,12 +104,13 @@ func ExecuteESQuery(index string, query map[string]interface{}) (InventoryRespon
 	}
 
 	// 执行查询
-	res, err := global.ESClient.Search(
-		global.ESClient.Search.WithContext(context.Background()),
-		global.ESClient.Search.WithIndex(index),
-		global.ESClient.Search.WithBody(&buf),
-		global.ESClient.Search.WithTrackTotalHits(true),
-		global.ESClient.Search.WithPretty(),
+	es := global.ESClient()
+	res, err := es.Search(
+		es.Search.WithContext(context.Background()),
+		es.Search.WithIndex(index),
+		es.Search.WithBody(&buf),
+		es.Search.WithTrackTotalHits(true),
+		es.Search.WithPretty(),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("search error: %w", err)
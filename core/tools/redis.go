@@ -9,7 +9,7 @@ import (
 // Lock 尝试获取锁
 func Lock(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
 	// 使用 SET 命令尝试获取锁，NX 表示只有当键不存在时才设置，EX 表示设置过期时间
-	set, err := global.RedisDB.SetNX(key, value, expiration).Result()
+	set, err := global.RedisDB().SetNX(key, value, expiration).Result()
 	if err != nil {
 		return false, err
 	}
@@ -25,7 +25,7 @@ func Unlock(ctx context.Context, keys []string, value string) (bool, error) {
 		else
     		return 0
 		end`
-	result, err := global.RedisDB.Eval(script, keys, value).Result()
+	result, err := global.RedisDB().Eval(script, keys, value).Result()
 
 	if err != nil {
 		return false, err
@@ -33,3 +33,20 @@ func Unlock(ctx context.Context, keys []string, value string) (bool, error) {
 	// 判断返回值是否为 1，表示删除成功
 	return result.(int64) == 1, nil
 }
+
+// Renew 续约锁，只有锁的持有者才能延长过期时间
+func Renew(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	// 使用 Lua 脚本保证原子性，先检查锁的值是否与当前值相同，相同则重新设置过期时间
+	script :=
+		`if redis.call("get", KEYS[1]) == ARGV[1] then
+    		return redis.call("pexpire", KEYS[1], ARGV[2])
+		else
+    		return 0
+		end`
+	result, err := global.RedisDB().Eval(script, []string{key}, value, expiration.Milliseconds()).Result()
+	if err != nil {
+		return false, err
+	}
+	// 判断返回值是否为 1，表示续约成功
+	return result.(int64) == 1, nil
+}
@@ -0,0 +1,18 @@
+package tools
+
+import (
+	"context"
+)
+
+type traceIDKey struct{}
+
+// WithTraceID 在 context 中写入链路追踪 ID
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext 从 context 中读取链路追踪 ID，不存在时返回空字符串
+func TraceIDFromContext(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
@@ -0,0 +1,35 @@
+package router
+
+import (
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"go-task-service/api"
+	"go-task-service/cmd/global"
+	"net/http"
+)
+
+// AdminAuth 校验管理接口的 X-Admin-Token，未配置 ADMIN_TOKEN 时拒绝所有请求
+func AdminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := global.Config().ADMINTOKEN
+		if token == "" {
+			api.Fail(c, http.StatusServiceUnavailable, "未配置 ADMIN_TOKEN，管理接口不可用")
+			c.Abort()
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Admin-Token")), []byte(token)) != 1 {
+			api.Fail(c, http.StatusUnauthorized, "管理接口鉴权失败")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// TrackClients 登记请求对全局客户端的使用，配置热更新替换客户端时等请求结束后再关闭旧连接
+func TrackClients() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer global.UseClients()()
+		c.Next()
+	}
+}
@@ -0,0 +1,40 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go-task-service/api"
+	"go-task-service/cmd/global"
+)
+
+// InitRouter 初始化路由并赋值给 global.Router
+func InitRouter() *gin.Engine {
+	r := gin.Default()
+
+	// prometheus 指标
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	admin := r.Group("/admin", AdminAuth(), TrackClients())
+	{
+		tasks := admin.Group("/tasks")
+		tasks.GET("", api.ListTasks)
+		tasks.GET("/:name/runs", api.ListTaskRuns)
+		tasks.POST("/:name/trigger", api.TriggerTask)
+		tasks.POST("/:name/pause", api.PauseTask)
+		tasks.POST("/:name/resume", api.ResumeTask)
+		tasks.PUT("/:name/spec", api.UpdateTaskSpec)
+
+		deadLetters := admin.Group("/dead-letters")
+		deadLetters.GET("", api.ListDeadLetters)
+		deadLetters.GET("/:id", api.GetDeadLetter)
+		deadLetters.POST("/:id/replay", api.ReplayDeadLetter)
+		deadLetters.POST("/:id/discard", api.DiscardDeadLetter)
+		deadLetters.POST("/replay", api.ReplayDeadLetters)
+		deadLetters.POST("/discard", api.DiscardDeadLetters)
+
+		admin.GET("/leader", api.LeaderStatus)
+	}
+
+	global.Router = r
+	return r
+}
@@ -0,0 +1,34 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"go-task-service/cmd/global"
+	"go-task-service/core/tools"
+	"time"
+)
+
+// fireLeaseTTL 单次触发的锁租期。锁按触发时间区分，执行结束后不主动释放，
+// 避免时钟略慢的实例在锁释放后再次执行同一次触发
+const fireLeaseTTL = 10 * time.Minute
+
+// scheduledFireTime 获取本次触发的计划时间，各实例按同一 spec 计算出的时间一致
+func scheduledFireTime(entryID cron.EntryID) time.Time {
+	if entryID != 0 {
+		if entry := global.Cron.Entry(entryID); !entry.Prev.IsZero() {
+			return entry.Prev
+		}
+	}
+	return time.Now().Truncate(time.Second)
+}
+
+// fireLeaseKey 集群锁的 key：任务名 + 触发时间
+func fireLeaseKey(name string, fireTime time.Time) string {
+	return fmt.Sprintf("scheduler:fire:%s:%d", name, fireTime.Unix())
+}
+
+// acquireFireLease 尝试获取本次触发的执行权，返回 false 表示已被其他实例抢到
+func acquireFireLease(ctx context.Context, name string, fireTime time.Time) (bool, error) {
+	return tools.Lock(ctx, fireLeaseKey(name, fireTime), instanceID, fireLeaseTTL)
+}
@@ -3,17 +3,32 @@ package scheduler
 import (
 	"github.com/robfig/cron/v3"
 	"go-task-service/cmd/global"
+	"go-task-service/core/curd_methods"
 	"log"
+	"time"
 )
 
 func InitScheduler() {
+	// 同步任务执行记录表结构
+	if err := curd_methods.AutoMigrateTaskRunRecord(); err != nil {
+		log.Fatalf("同步任务执行记录表失败: %v", err)
+	}
+
 	// 初始化定时任务调度器
 	global.Cron = cron.New(cron.WithSeconds())
 
 	// 注册定时任务
 	registerTasks()
 
-	// 启动定时任务调度器
+	// 加载 nacos 中的任务调度配置并监听变更
+	loadTaskSchedules()
+
+	// 开启选主时由 leader 启动调度器，否则直接启动
+	if global.Config().SCHEDULERLEADERELECTION {
+		startLeaderElection(time.Duration(global.Config().SCHEDULERLEADERTTL) * time.Second)
+		log.Println("定时任务调度器已开启选主，等待当选 leader")
+		return
+	}
 	global.Cron.Start()
 	log.Println("定时任务调度器已启动")
 }
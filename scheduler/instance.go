@@ -0,0 +1,20 @@
+package scheduler
+
+import (
+	"fmt"
+	"os"
+)
+
+// instanceID 当前实例标识，用于集群锁的持有者和执行记录
+var instanceID = func() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}()
+
+// InstanceID 返回当前实例标识
+func InstanceID() string {
+	return instanceID
+}
@@ -0,0 +1,180 @@
+package scheduler
+
+import (
+	"context"
+	"go-task-service/cmd/global"
+	"go-task-service/core/tools"
+	"go.uber.org/zap"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	leaderKey     = "scheduler:leader"
+	leaderTermKey = "scheduler:leader:term"
+	// 未配置 SCHEDULER_LEADER_TTL 时的默认租期
+	defaultLeaderTTL = 15 * time.Second
+)
+
+// LeaderStatus 选主状态，供管理接口查询
+type LeaderStatus struct {
+	Enabled  bool   `json:"enabled"`
+	Instance string `json:"instance"`
+	IsLeader bool   `json:"is_leader"`
+	Leader   string `json:"leader"`
+	Term     int64  `json:"term"`
+	TTL      string `json:"ttl"`
+}
+
+// leaderElector 基于 Redis 租约的选主，只有 leader 实例运行 global.Cron。
+// leader 每 ttl/3 续约一次，宕机后备用实例最迟在 ttl + ttl/3 内接管
+type leaderElector struct {
+	ttl      time.Duration
+	interval time.Duration
+
+	mu        sync.Mutex
+	isLeader  bool
+	term      int64
+	lastRenew time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+var elector *leaderElector
+
+func newLeaderElector(ttl time.Duration) *leaderElector {
+	if ttl <= 0 {
+		ttl = defaultLeaderTTL
+	}
+	return &leaderElector{
+		ttl:      ttl,
+		interval: ttl / 3,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (e *leaderElector) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	e.tick()
+	for {
+		select {
+		case <-e.stop:
+			e.resign()
+			return
+		case <-ticker.C:
+			e.tick()
+		}
+	}
+}
+
+func (e *leaderElector) tick() {
+	ctx := context.Background()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.isLeader {
+		ok, err := tools.Renew(ctx, leaderKey, instanceID, e.ttl)
+		if err != nil {
+			zap.L().Error("续约 leader 租约失败", zap.Error(err))
+			// 租约可能已在 Redis 上过期，超过安全时间仍未续约成功则主动退位，避免双主
+			if time.Since(e.lastRenew) >= e.ttl-e.interval {
+				e.stepDownLocked("续约超时")
+			}
+			return
+		}
+		if !ok {
+			e.stepDownLocked("租约已被其他实例持有")
+			return
+		}
+		e.lastRenew = time.Now()
+		return
+	}
+
+	ok, err := tools.Lock(ctx, leaderKey, instanceID, e.ttl)
+	if err != nil {
+		zap.L().Error("竞选 leader 失败", zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
+	term, err := global.RedisDB().Incr(leaderTermKey).Result()
+	if err != nil {
+		// 拿到租约但任期号递增失败，仍然当选，任期号仅用于展示
+		zap.L().Error("递增 leader 任期失败", zap.Error(err))
+	}
+	e.isLeader = true
+	e.term = term
+	e.lastRenew = time.Now()
+	global.Cron.Start()
+	zap.L().Info("当选 leader，启动定时任务调度器", zap.String("instance", instanceID), zap.Int64("term", term))
+}
+
+func (e *leaderElector) stepDownLocked(reason string) {
+	e.isLeader = false
+	global.Cron.Stop()
+	zap.L().Warn("失去 leader 身份，停止定时任务调度器", zap.String("instance", instanceID), zap.String("reason", reason))
+}
+
+// resign 退出时主动释放租约，让备用实例立即接管
+func (e *leaderElector) resign() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.isLeader {
+		return
+	}
+	e.stepDownLocked("实例退出")
+	if _, err := tools.Unlock(context.Background(), []string{leaderKey}, instanceID); err != nil {
+		zap.L().Error("释放 leader 租约失败", zap.Error(err))
+	}
+}
+
+func (e *leaderElector) status() LeaderStatus {
+	e.mu.Lock()
+	status := LeaderStatus{
+		Enabled:  true,
+		Instance: instanceID,
+		IsLeader: e.isLeader,
+		Term:     e.term,
+		TTL:      e.ttl.String(),
+	}
+	e.mu.Unlock()
+
+	if leader, err := global.RedisDB().Get(leaderKey).Result(); err == nil {
+		status.Leader = leader
+	}
+	if term, err := global.RedisDB().Get(leaderTermKey).Result(); err == nil {
+		if n, err := strconv.ParseInt(term, 10, 64); err == nil {
+			status.Term = n
+		}
+	}
+	return status
+}
+
+// startLeaderElection 开启选主，当选后才启动 global.Cron
+func startLeaderElection(ttl time.Duration) {
+	elector = newLeaderElector(ttl)
+	go elector.run()
+}
+
+// StopLeaderElection 停止选主并释放租约，未开启选主时直接返回
+func StopLeaderElection() {
+	if elector == nil {
+		return
+	}
+	close(elector.stop)
+	<-elector.done
+}
+
+// GetLeaderStatus 返回当前 leader 和任期
+func GetLeaderStatus() LeaderStatus {
+	if elector == nil {
+		return LeaderStatus{Enabled: false, Instance: instanceID, IsLeader: true}
+	}
+	return elector.status()
+}
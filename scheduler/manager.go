@@ -0,0 +1,265 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"go-task-service/cmd/global"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+var ErrTaskNotFound = errors.New("任务不存在")
+
+// ErrScheduleNotShared 未配置任务调度 dataId，暂停、恢复和修改 spec 无法同步到集群内所有实例，
+// 只有开启 SCHEDULER_REQUIRE_SHARED 时返回
+var ErrScheduleNotShared = errors.New("未配置任务调度 dataId，无法修改任务调度")
+
+// specParser 与 cron.WithSeconds 使用相同的解析规则
+var specParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// taskState 任务在调度器中的当前状态
+type taskState struct {
+	task    Task
+	spec    string
+	entryID cron.EntryID
+	paused  bool
+}
+
+var (
+	stateMu sync.Mutex
+	states  = make(map[string]*taskState)
+)
+
+// TaskInfo 管理接口返回的任务信息
+type TaskInfo struct {
+	Name    string     `json:"name"`
+	Spec    string     `json:"spec"`
+	Paused  bool       `json:"paused"`
+	EntryID int        `json:"entry_id"`
+	NextRun *time.Time `json:"next_run"`
+	PrevRun *time.Time `json:"prev_run"`
+}
+
+// scheduleLocked 把任务按 spec 添加到调度器，调用方需持有 stateMu
+func scheduleLocked(state *taskState, spec string) error {
+	var entryID cron.EntryID
+	entryID, err := global.Cron.AddFunc(spec, func() {
+		// entryID 在 AddFunc 返回后才赋值，调度器运行中时任务可能先被触发，
+		// 赋值发生在调用方持有 stateMu 期间，这里同样在 stateMu 下读取
+		stateMu.Lock()
+		id := entryID
+		stateMu.Unlock()
+		runScheduled(state.task, id)
+	})
+	if err != nil {
+		return fmt.Errorf("添加任务 %s 失败: %w", state.task.Name(), err)
+	}
+	state.entryID = entryID
+	state.spec = spec
+	return nil
+}
+
+// taskInterval 按任务当前的 spec 计算从 now 起相邻两次触发间隔中较大的一个，
+// 任务不存在或 spec 无法解析时返回 0
+func taskInterval(name string, now time.Time) time.Duration {
+	stateMu.Lock()
+	state, ok := states[name]
+	var spec string
+	if ok {
+		spec = state.spec
+	}
+	stateMu.Unlock()
+	if !ok {
+		task, found := LookupTask(name)
+		if !found {
+			return 0
+		}
+		spec = task.Spec()
+	}
+	schedule, err := specParser.Parse(spec)
+	if err != nil {
+		return 0
+	}
+	first := schedule.Next(now)
+	second := schedule.Next(first)
+	third := schedule.Next(second)
+	return max(second.Sub(first), third.Sub(second))
+}
+
+func lookupState(name string) (*taskState, error) {
+	state, ok := states[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, name)
+	}
+	return state, nil
+}
+
+// ListTasks 返回所有任务及其上次、下次执行时间
+func ListTasks() []TaskInfo {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	infos := make([]TaskInfo, 0, len(states))
+	for _, task := range Tasks() {
+		state, ok := states[task.Name()]
+		if !ok {
+			continue
+		}
+		info := TaskInfo{
+			Name:    task.Name(),
+			Spec:    state.spec,
+			Paused:  state.paused,
+			EntryID: int(state.entryID),
+		}
+		if !state.paused {
+			entry := global.Cron.Entry(state.entryID)
+			if !entry.Next.IsZero() {
+				next := entry.Next
+				info.NextRun = &next
+			}
+			if !entry.Prev.IsZero() {
+				prev := entry.Prev
+				info.PrevRun = &prev
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// TriggerTask 立即异步执行一次任务，不影响原有调度
+func TriggerTask(name string) error {
+	stateMu.Lock()
+	state, err := lookupState(name)
+	stateMu.Unlock()
+	if err != nil {
+		return err
+	}
+	if !beginRun() {
+		return ErrShuttingDown
+	}
+	go runManual(state.task)
+	return nil
+}
+
+// PauseTask 暂停任务。修改写入 nacos 任务调度配置，由各实例监听到变更后统一生效，
+// 避免只有收到请求的实例暂停，未配置任务调度 dataId 时见 shareTaskSchedule
+func PauseTask(name string) error {
+	if _, ok := LookupTask(name); !ok {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, name)
+	}
+	enabled := false
+	if err := shareTaskSchedule(name, func(cfg *TaskScheduleConfig) {
+		cfg.Enabled = &enabled
+	}); err != nil {
+		return err
+	}
+	// 本实例立即生效，之后收到的推送不会再有变化
+	return pauseTask(name)
+}
+
+// ResumeTask 恢复已暂停的任务，与 PauseTask 一样通过 nacos 同步到所有实例
+func ResumeTask(name string) error {
+	if _, ok := LookupTask(name); !ok {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, name)
+	}
+	enabled := true
+	if err := shareTaskSchedule(name, func(cfg *TaskScheduleConfig) {
+		cfg.Enabled = &enabled
+	}); err != nil {
+		return err
+	}
+	return resumeTask(name)
+}
+
+// UpdateTaskSpec 修改任务的 cron 表达式，与 PauseTask 一样通过 nacos 同步到所有实例，
+// 保证各实例按同一 spec 计算触发时间，集群锁才能去重
+func UpdateTaskSpec(name, spec string) error {
+	if _, ok := LookupTask(name); !ok {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, name)
+	}
+	if _, err := specParser.Parse(spec); err != nil {
+		return fmt.Errorf("cron 表达式不合法: %w", err)
+	}
+	if err := shareTaskSchedule(name, func(cfg *TaskScheduleConfig) {
+		cfg.Spec = spec
+	}); err != nil {
+		return err
+	}
+	return updateTaskSpec(name, spec)
+}
+
+// shareTaskSchedule 把管理接口的修改写入 nacos 任务调度配置。未配置 nacos 或任务调度 dataId 时
+// （如使用快照或本地配置启动），除非开启 SCHEDULER_REQUIRE_SHARED，否则只记录警告，修改只在本实例生效
+func shareTaskSchedule(name string, update func(cfg *TaskScheduleConfig)) error {
+	err := updateTaskScheduleConfig(name, update)
+	if errors.Is(err, ErrScheduleNotShared) && !global.Config().SCHEDULERREQUIRESHARED {
+		zap.L().Warn("未配置任务调度 dataId，修改只在本实例生效，其他实例和重启后仍使用原调度", zap.String("task", name))
+		return nil
+	}
+	return err
+}
+
+// pauseTask 在本实例暂停任务，从调度器中移除但保留其配置
+func pauseTask(name string) error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	state, err := lookupState(name)
+	if err != nil {
+		return err
+	}
+	if state.paused {
+		return nil
+	}
+	global.Cron.Remove(state.entryID)
+	state.entryID = 0
+	state.paused = true
+	return nil
+}
+
+// resumeTask 在本实例恢复已暂停的任务
+func resumeTask(name string) error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	state, err := lookupState(name)
+	if err != nil {
+		return err
+	}
+	if !state.paused {
+		return nil
+	}
+	if err := scheduleLocked(state, state.spec); err != nil {
+		return err
+	}
+	state.paused = false
+	return nil
+}
+
+// updateTaskSpec 在本实例修改任务的 cron 表达式，暂停中的任务只更新配置
+func updateTaskSpec(name, spec string) error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	state, err := lookupState(name)
+	if err != nil {
+		return err
+	}
+	if state.spec == spec {
+		return nil
+	}
+	if state.paused {
+		// 先校验表达式，恢复时再添加到调度器
+		if _, err := specParser.Parse(spec); err != nil {
+			return fmt.Errorf("cron 表达式不合法: %w", err)
+		}
+		state.spec = spec
+		return nil
+	}
+	// 先添加新的调度，成功后再移除旧的，避免表达式错误导致任务丢失
+	oldEntryID := state.entryID
+	if err := scheduleLocked(state, spec); err != nil {
+		return err
+	}
+	global.Cron.Remove(oldEntryID)
+	return nil
+}
@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/robfig/cron/v3"
+	"go-task-service/cmd/appconf"
+	"go-task-service/cmd/global"
+	"testing"
+	"time"
+)
+
+// withLocalTask 在未连接 nacos 的调度器中注册一个只在测试中使用的任务
+func withLocalTask(t *testing.T, name, spec string) {
+	t.Helper()
+	oldCron, oldClient, oldNacos := global.Cron, global.NacosClient, global.Nacos
+	global.Cron = cron.New(cron.WithSeconds())
+	global.NacosClient, global.Nacos = nil, nil
+	oldConf := global.Config()
+	t.Cleanup(func() {
+		global.Cron, global.NacosClient, global.Nacos = oldCron, oldClient, oldNacos
+		global.SetConfig(oldConf)
+		registryMu.Lock()
+		delete(registry, name)
+		for i, n := range registryOrder {
+			if n == name {
+				registryOrder = append(registryOrder[:i], registryOrder[i+1:]...)
+				break
+			}
+		}
+		registryMu.Unlock()
+		stateMu.Lock()
+		delete(states, name)
+		stateMu.Unlock()
+	})
+
+	task := NewTask(name, spec, func(ctx context.Context) error { return nil })
+	if err := Register(task); err != nil {
+		t.Fatal(err)
+	}
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	state := &taskState{task: task}
+	if err := scheduleLocked(state, spec); err != nil {
+		t.Fatal(err)
+	}
+	states[name] = state
+}
+
+func taskInfo(t *testing.T, name string) TaskInfo {
+	t.Helper()
+	for _, info := range ListTasks() {
+		if info.Name == name {
+			return info
+		}
+	}
+	t.Fatalf("task %s not listed", name)
+	return TaskInfo{}
+}
+
+func TestScheduleChangesWithoutNacosApplyLocally(t *testing.T) {
+	const name = "test_local_schedule"
+	withLocalTask(t, name, "0 0 * * * *")
+	global.SetConfig(&appconf.AppConfigMaster{})
+
+	if err := PauseTask(name); err != nil {
+		t.Fatalf("PauseTask() error = %v", err)
+	}
+	if info := taskInfo(t, name); !info.Paused {
+		t.Errorf("task not paused: %+v", info)
+	}
+	if err := UpdateTaskSpec(name, "0 30 * * * *"); err != nil {
+		t.Fatalf("UpdateTaskSpec() error = %v", err)
+	}
+	if err := ResumeTask(name); err != nil {
+		t.Fatalf("ResumeTask() error = %v", err)
+	}
+	info := taskInfo(t, name)
+	if info.Paused || info.Spec != "0 30 * * * *" || info.EntryID == 0 {
+		t.Errorf("task after resume = %+v, want running with new spec", info)
+	}
+}
+
+func TestScheduleChangesWithoutNacosRequireShared(t *testing.T) {
+	const name = "test_shared_schedule"
+	withLocalTask(t, name, "0 0 * * * *")
+	global.SetConfig(&appconf.AppConfigMaster{SCHEDULERREQUIRESHARED: true})
+
+	if err := PauseTask(name); !errors.Is(err, ErrScheduleNotShared) {
+		t.Fatalf("PauseTask() error = %v, want ErrScheduleNotShared", err)
+	}
+	if err := UpdateTaskSpec(name, "0 30 * * * *"); !errors.Is(err, ErrScheduleNotShared) {
+		t.Fatalf("UpdateTaskSpec() error = %v, want ErrScheduleNotShared", err)
+	}
+	// 拒绝时本实例也不做修改
+	if info := taskInfo(t, name); info.Paused || info.Spec != "0 0 * * * *" {
+		t.Errorf("task changed after rejected request: %+v", info)
+	}
+}
+
+func TestTaskInterval(t *testing.T) {
+	now := time.Date(2026, 1, 1, 1, 0, 0, 0, time.Local)
+	tests := []struct {
+		spec string
+		want time.Duration
+	}{
+		{"0 0 */6 * * *", 6 * time.Hour},
+		{"0 */15 * * * *", 15 * time.Minute},
+		// 间隔不均匀时取较大的一个
+		{"0 0 0,20 * * *", 20 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			withLocalTask(t, "test_interval", tt.spec)
+			if got := taskInterval("test_interval", now); got != tt.want {
+				t.Errorf("taskInterval(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+	if got := taskInterval("test_missing", now); got != 0 {
+		t.Errorf("taskInterval(missing) = %v, want 0", got)
+	}
+}
+
+func TestInventoryQueuedTTLCoversSweepInterval(t *testing.T) {
+	oldConf := global.Config()
+	t.Cleanup(func() { global.SetConfig(oldConf) })
+	global.SetConfig(&appconf.AppConfigMaster{})
+	now := time.Date(2026, 1, 1, 1, 0, 0, 0, time.Local)
+	// 默认每 6 小时扫描一次、超时 30 分钟，标记需保留到下一次扫描结束
+	if got, want := inventoryQueuedTTL(now), 6*time.Hour+30*time.Minute; got != want {
+		t.Errorf("inventoryQueuedTTL() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,30 @@
+package scheduler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// taskRunsTotal 任务执行次数，按结果状态区分
+	taskRunsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_task_runs_total",
+		Help: "定时任务执行次数",
+	}, []string{"task", "status"})
+
+	// taskRunDuration 任务执行耗时
+	taskRunDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "scheduler_task_run_duration_seconds",
+		Help:    "定时任务执行耗时",
+		Buckets: []float64{0.1, 0.5, 1, 5, 15, 30, 60, 300, 900, 1800, 3600},
+	}, []string{"task"})
+
+	// taskOverlapTotal 因上一次执行未结束而被跳过或延迟的触发次数
+	taskOverlapTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_task_overlap_total",
+		Help: "定时任务因上一次执行未结束而跳过或延迟的次数",
+	}, []string{"task", "action"})
+)
+
+func init() {
+	prometheus.MustRegister(taskRunsTotal, taskRunDuration, taskOverlapTotal)
+}
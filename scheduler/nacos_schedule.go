@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-task-service/cmd/global"
+	"go.uber.org/zap"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+)
+
+// TaskScheduleConfig nacos 中单个任务的调度配置，dataId 内容形如
+//
+//	{"update_inventory": {"spec": "0 0 */12 * * *", "enabled": true}}
+//
+// 只处理配置中列出的任务和字段，未列出的任务、为空的 spec 和缺省的 enabled 保持当前状态，
+// 避免修改其他任务时覆盖管理接口做过的调整
+type TaskScheduleConfig struct {
+	Spec    string `json:"spec,omitempty"`
+	Enabled *bool  `json:"enabled,omitempty"`
+}
+
+// 发布任务调度配置时 casMd5 冲突的最大重试次数
+const maxSchedulePublishAttempts = 3
+
+// loadTaskSchedules 读取 nacos 中的任务调度配置并监听变更，未配置 TaskDataId 时直接返回
+func loadTaskSchedules() {
+	if global.NacosClient == nil || global.Nacos == nil || global.Nacos.TaskDataId == "" {
+		return
+	}
+	param := vo.ConfigParam{
+		DataId: global.Nacos.TaskDataId,
+		Group:  global.Nacos.Group,
+	}
+	content, err := global.NacosClient.GetConfig(param)
+	if err != nil {
+		// 读取失败时沿用默认调度，等待监听推送
+		zap.L().Error("读取任务调度配置失败，使用默认调度", zap.String("dataId", param.DataId), zap.Error(err))
+	} else {
+		applyTaskSchedules(content)
+	}
+
+	param.OnChange = func(namespace, group, dataId, data string) {
+		zap.L().Info("任务调度配置发生变更", zap.String("dataId", dataId))
+		applyTaskSchedules(data)
+	}
+	if err := global.NacosClient.ListenConfig(param); err != nil {
+		zap.L().Error("监听任务调度配置失败", zap.String("dataId", param.DataId), zap.Error(err))
+	}
+}
+
+// stopTaskSchedules 取消对任务调度配置的监听
+func stopTaskSchedules() {
+	if global.NacosClient == nil || global.Nacos == nil || global.Nacos.TaskDataId == "" {
+		return
+	}
+	err := global.NacosClient.CancelListenConfig(vo.ConfigParam{
+		DataId: global.Nacos.TaskDataId,
+		Group:  global.Nacos.Group,
+	})
+	if err != nil {
+		zap.L().Error("取消监听任务调度配置失败", zap.Error(err))
+	}
+}
+
+// updateTaskScheduleConfig 修改 nacos 任务调度配置中单个任务的配置并发布，各实例（包括本实例）
+// 通过监听收到变更后生效。发布时带上读取到的内容的 md5，其他人同时修改时重新读取再修改
+func updateTaskScheduleConfig(name string, update func(cfg *TaskScheduleConfig)) error {
+	if global.NacosClient == nil || global.Nacos == nil || global.Nacos.TaskDataId == "" {
+		return ErrScheduleNotShared
+	}
+	param := vo.ConfigParam{
+		DataId: global.Nacos.TaskDataId,
+		Group:  global.Nacos.Group,
+	}
+	var lastErr error
+	for attempt := 1; attempt <= maxSchedulePublishAttempts; attempt++ {
+		content, err := global.NacosClient.GetConfig(param)
+		if err != nil {
+			return fmt.Errorf("读取任务调度配置失败: %w", err)
+		}
+		configs := make(map[string]TaskScheduleConfig)
+		if content != "" {
+			if err := json.Unmarshal([]byte(content), &configs); err != nil {
+				return fmt.Errorf("解析任务调度配置失败: %w", err)
+			}
+		}
+		cfg := configs[name]
+		update(&cfg)
+		configs[name] = cfg
+		data, err := json.MarshalIndent(configs, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		publish := param
+		publish.Content = string(data)
+		publish.Type = "json"
+		if content != "" {
+			sum := md5.Sum([]byte(content))
+			publish.CasMd5 = hex.EncodeToString(sum[:])
+		}
+		ok, err := global.NacosClient.PublishConfig(publish)
+		if err == nil && ok {
+			zap.L().Info("任务调度配置已发布", zap.String("task", name), zap.String("dataId", param.DataId))
+			return nil
+		}
+		if err == nil {
+			err = errors.New("nacos 返回发布失败")
+		}
+		lastErr = err
+		zap.L().Warn("发布任务调度配置失败，重新读取后重试", zap.String("task", name), zap.Int("attempt", attempt), zap.Error(err))
+	}
+	return fmt.Errorf("发布任务调度配置失败: %w", lastErr)
+}
+
+// applyTaskSchedules 把配置中列出的任务的 spec 和启用状态应用到调度器，只处理发生变化的任务
+func applyTaskSchedules(content string) {
+	configs := make(map[string]TaskScheduleConfig)
+	if content != "" {
+		if err := json.Unmarshal([]byte(content), &configs); err != nil {
+			zap.L().Error("解析任务调度配置失败，保持当前调度", zap.Error(err))
+			return
+		}
+	}
+
+	for name, cfg := range configs {
+		if _, ok := LookupTask(name); !ok {
+			zap.L().Warn("任务调度配置中存在未注册的任务", zap.String("task", name))
+			continue
+		}
+		if cfg.Spec != "" {
+			if err := updateTaskSpec(name, cfg.Spec); err != nil {
+				zap.L().Error("更新任务 spec 失败", zap.String("task", name), zap.String("spec", cfg.Spec), zap.Error(err))
+			}
+		}
+		if cfg.Enabled == nil {
+			continue
+		}
+		var err error
+		if *cfg.Enabled {
+			err = resumeTask(name)
+		} else {
+			err = pauseTask(name)
+		}
+		if err != nil {
+			zap.L().Error("更新任务启用状态失败", zap.String("task", name), zap.Bool("enabled", *cfg.Enabled), zap.Error(err))
+		}
+	}
+}
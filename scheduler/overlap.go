@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-task-service/core/tools"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	// overlapLockTTL 任务并发锁的租期，执行期间每 overlapLockTTL/3 续约一次，
+	// 实例宕机后锁最迟在一个租期后释放
+	overlapLockTTL = 30 * time.Second
+	// overlapPollInterval OverlapDelay 等待上一次执行结束时重试加锁的间隔
+	overlapPollInterval = time.Second
+)
+
+// errOverlapSkipped 按 OverlapSkip 策略跳过本次触发
+var errOverlapSkipped = errors.New("上一次执行尚未结束")
+
+// overlapLockKey 任务并发锁的 key，集群内同一任务共用一把锁
+func overlapLockKey(name string) string {
+	return "scheduler:running:" + name
+}
+
+// acquireOverlap 按任务的并发策略获取集群内的执行权，返回 release 用于执行结束后释放。
+// 返回 errOverlapSkipped 表示本次触发按策略被跳过，ErrShuttingDown 表示等待期间调度器开始退出
+func acquireOverlap(name string, policy OverlapPolicy) (release func(), err error) {
+	if policy == OverlapAllow {
+		return func() {}, nil
+	}
+	key := overlapLockKey(name)
+	// 每次加锁使用不同的值，保证只释放自己持有的锁
+	value := fmt.Sprintf("%s:%d", instanceID, time.Now().UnixNano())
+	acquired, err := tools.Lock(baseCtx, key, value, overlapLockTTL)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务并发锁失败: %w", err)
+	}
+	if acquired {
+		return holdOverlapLock(name, key, value), nil
+	}
+	if policy == OverlapSkip {
+		taskOverlapTotal.WithLabelValues(name, "skipped").Inc()
+		zap.L().Warn("上一次执行尚未结束，跳过本次触发", zap.String("task", name))
+		return nil, errOverlapSkipped
+	}
+
+	taskOverlapTotal.WithLabelValues(name, "delayed").Inc()
+	zap.L().Warn("上一次执行尚未结束，等待其结束后执行", zap.String("task", name))
+	ticker := time.NewTicker(overlapPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-baseCtx.Done():
+			return nil, ErrShuttingDown
+		case <-ticker.C:
+		}
+		if shuttingDown.Load() {
+			return nil, ErrShuttingDown
+		}
+		acquired, err := tools.Lock(baseCtx, key, value, overlapLockTTL)
+		if err != nil {
+			zap.L().Error("获取任务并发锁失败，稍后重试", zap.String("task", name), zap.Error(err))
+			continue
+		}
+		if !acquired {
+			continue
+		}
+		release := holdOverlapLock(name, key, value)
+		// 等待期间调度器可能已开始退出，拿到锁后不再执行
+		if shuttingDown.Load() {
+			release()
+			return nil, ErrShuttingDown
+		}
+		return release, nil
+	}
+}
+
+// holdOverlapLock 在执行期间定时续约并发锁，返回的 release 停止续约并释放锁
+func holdOverlapLock(name, key, value string) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(overlapLockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			ok, err := tools.Renew(context.Background(), key, value, overlapLockTTL)
+			if err != nil {
+				zap.L().Error("续约任务并发锁失败", zap.String("task", name), zap.Error(err))
+				continue
+			}
+			if !ok {
+				zap.L().Warn("任务并发锁已失效，其他实例可能同时执行", zap.String("task", name))
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+		if _, err := tools.Unlock(context.Background(), []string{key}, value); err != nil {
+			zap.L().Error("释放任务并发锁失败", zap.String("task", name), zap.Error(err))
+		}
+	}
+}
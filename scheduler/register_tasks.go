@@ -1,22 +1,18 @@
 package scheduler
 
 import (
-	"go-task-service/cmd/global"
 	"log"
 )
 
+// registerTasks 把注册表中的任务全部添加到调度器
 func registerTasks() {
-	tasks := []struct {
-		Spec string
-		Job  func()
-	}{
-		{"0 0 */6 * * *", UpdateInventoryTask},
-	}
-
-	for _, task := range tasks {
-		_, err := global.Cron.AddFunc(task.Spec, task.Job)
-		if err != nil {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	for _, task := range Tasks() {
+		state := &taskState{task: task}
+		if err := scheduleLocked(state, task.Spec()); err != nil {
 			log.Fatalf("注册任务失败: %v", err)
 		}
+		states[task.Name()] = state
 	}
 }
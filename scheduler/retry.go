@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy 任务失败后的重试策略，每次重试都会单独写一条执行记录
+type RetryPolicy struct {
+	// MaxAttempts 最大执行次数（包含第一次），小于等于 1 表示不重试
+	MaxAttempts int
+	// InitialBackoff 第一次重试前的等待时间，之后每次翻倍
+	InitialBackoff time.Duration
+	// MaxBackoff 等待时间上限
+	MaxBackoff time.Duration
+	// Jitter 随机抖动比例，取值 0-1，例如 0.2 表示在 ±20% 范围内随机
+	Jitter float64
+	// Retryable 判断错误是否可重试，为 nil 时所有错误都重试
+	Retryable func(err error) bool
+}
+
+// WithRetry 设置任务的重试策略
+func WithRetry(policy RetryPolicy) TaskOption {
+	return func(o *taskOptions) {
+		o.retry = policy
+	}
+}
+
+// shouldRetry 第 attempt 次执行失败后是否继续重试
+func (p RetryPolicy) shouldRetry(err error, attempt int) bool {
+	if err == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	if p.Retryable == nil {
+		return true
+	}
+	return p.Retryable(err)
+}
+
+// backoff 第 attempt 次执行失败后的等待时间
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		delta := float64(d) * p.Jitter
+		d = time.Duration(float64(d) - delta + rand.Float64()*2*delta)
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
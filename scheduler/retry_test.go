@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{"first retry", RetryPolicy{InitialBackoff: time.Second}, 1, time.Second},
+		{"doubles", RetryPolicy{InitialBackoff: time.Second}, 3, 4 * time.Second},
+		{"capped", RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, 4, 5 * time.Second},
+		{"capped far beyond", RetryPolicy{InitialBackoff: time.Second, MaxBackoff: time.Minute}, 100, time.Minute},
+		{"zero initial", RetryPolicy{}, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.backoff(tt.attempt); got != tt.want {
+				t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoffJitter(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, Jitter: 0.2}
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{1, 800 * time.Millisecond, 1200 * time.Millisecond},
+		{2, 1600 * time.Millisecond, 2400 * time.Millisecond},
+		{10, 8 * time.Second, 12 * time.Second},
+	}
+	for _, tt := range tests {
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < 200; i++ {
+			got := policy.backoff(tt.attempt)
+			if got < tt.min || got > tt.max {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", tt.attempt, got, tt.min, tt.max)
+			}
+			seen[got] = true
+		}
+		if len(seen) < 2 {
+			t.Errorf("backoff(%d) 没有随机抖动", tt.attempt)
+		}
+	}
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	errTemp := errors.New("temporary")
+	errFatal := errors.New("fatal")
+	onlyTemp := func(err error) bool { return errors.Is(err, errTemp) }
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		err     error
+		attempt int
+		want    bool
+	}{
+		{"success", RetryPolicy{MaxAttempts: 3}, nil, 1, false},
+		{"no retry configured", RetryPolicy{}, errTemp, 1, false},
+		{"single attempt", RetryPolicy{MaxAttempts: 1}, errTemp, 1, false},
+		{"retry", RetryPolicy{MaxAttempts: 3}, errTemp, 1, true},
+		{"last retry", RetryPolicy{MaxAttempts: 3}, errTemp, 2, true},
+		{"attempts exhausted", RetryPolicy{MaxAttempts: 3}, errTemp, 3, false},
+		{"retryable", RetryPolicy{MaxAttempts: 3, Retryable: onlyTemp}, errTemp, 1, true},
+		{"not retryable", RetryPolicy{MaxAttempts: 3, Retryable: onlyTemp}, errFatal, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.shouldRetry(tt.err, tt.attempt); got != tt.want {
+				t.Errorf("shouldRetry(%v, %d) = %v, want %v", tt.err, tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
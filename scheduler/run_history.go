@@ -0,0 +1,254 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"go-task-service/cmd/global"
+	"go-task-service/core/curd_methods"
+	"go-task-service/core/tools"
+	"go.uber.org/zap"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+type runStatsKey struct{}
+
+// runStats 单次执行过程中的统计信息
+type runStats struct {
+	processed atomic.Int64
+}
+
+// AddProcessed 在任务执行过程中累加已处理条数，最终写入执行记录
+func AddProcessed(ctx context.Context, n int64) {
+	if stats, ok := ctx.Value(runStatsKey{}).(*runStats); ok {
+		stats.processed.Add(n)
+	}
+}
+
+type runInfoKey struct{}
+
+// RunInfo 当前执行对应的触发信息，任务可通过 RunInfoFromContext 获取
+type RunInfo struct {
+	TaskName string
+	FireID   string
+	Attempt  int
+	// Manual 是否为管理接口手动触发
+	Manual bool
+}
+
+// RunInfoFromContext 从任务的 ctx 中读取触发信息
+func RunInfoFromContext(ctx context.Context) (RunInfo, bool) {
+	info, ok := ctx.Value(runInfoKey{}).(RunInfo)
+	return info, ok
+}
+
+// fire 一次任务触发，重试产生的多次执行共用同一个 fire
+type fire struct {
+	entryID  cron.EntryID
+	fireTime time.Time
+	// fireID 同一次触发的唯一标识，用于在执行记录中关联各次重试，同时作为链路追踪 ID
+	fireID string
+	manual bool
+}
+
+func newFire(entryID cron.EntryID, fireTime time.Time, manual bool) fire {
+	return fire{
+		entryID:  entryID,
+		fireTime: fireTime,
+		fireID:   fmt.Sprintf("%s@%d", instanceID, fireTime.UnixMilli()),
+		manual:   manual,
+	}
+}
+
+// runScheduled 调度器触发任务的入口，先抢占本次触发的集群锁，抢到后才执行
+func runScheduled(task Task, entryID cron.EntryID) {
+	if !beginRun() {
+		return
+	}
+	defer running.Done()
+	defer global.UseClients()()
+	defer recoverPanic(task.Name())
+	f := newFire(entryID, scheduledFireTime(entryID), false)
+	acquired, err := acquireFireLease(baseCtx, task.Name(), f.fireTime)
+	if err != nil {
+		// 拿不到锁时不执行，宁可少跑一次也不重复推送
+		zap.L().Error("获取任务执行锁失败", zap.String("task", task.Name()), zap.Error(err))
+		recordFinishedRun(task.Name(), f, curd_methods.TaskRunStatusFailed, "获取任务执行锁失败: "+err.Error())
+		return
+	}
+	if !acquired {
+		zap.L().Info("本次触发已由其他实例执行，跳过", zap.String("task", task.Name()), zap.Time("fire_time", f.fireTime))
+		recordFinishedRun(task.Name(), f, curd_methods.TaskRunStatusSkipped, "本次触发已由其他实例执行")
+		return
+	}
+	execute(task, f)
+}
+
+// runManual 手动触发任务，只在收到请求的实例上执行，不参与集群锁。
+// 调用方需先通过 beginRun 登记
+func runManual(task Task) {
+	defer running.Done()
+	defer global.UseClients()()
+	defer recoverPanic(task.Name())
+	execute(task, newFire(0, time.Now(), true))
+}
+
+// execute 按任务的并发策略和重试策略执行一次触发
+func execute(task Task, f fire) {
+	options := lookupOptions(task.Name())
+	release, err := acquireOverlap(task.Name(), options.overlap)
+	if err != nil {
+		status := curd_methods.TaskRunStatusSkipped
+		if !errors.Is(err, errOverlapSkipped) && !errors.Is(err, ErrShuttingDown) {
+			status = curd_methods.TaskRunStatusFailed
+			zap.L().Error("获取任务并发锁失败", zap.String("task", task.Name()), zap.Error(err))
+		}
+		recordFinishedRun(task.Name(), f, status, err.Error())
+		return
+	}
+	defer release()
+
+	for attempt := 1; ; attempt++ {
+		err := runWithHistory(task, options, f, attempt)
+		if !options.retry.shouldRetry(err, attempt) {
+			return
+		}
+		backoff := options.retry.backoff(attempt)
+		zap.L().Warn("定时任务执行失败，等待重试",
+			zap.String("task", task.Name()),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		select {
+		case <-time.After(backoff):
+		case <-baseCtx.Done():
+			return
+		}
+		if shuttingDown.Load() {
+			zap.L().Warn("调度器正在退出，放弃重试", zap.String("task", task.Name()))
+			return
+		}
+	}
+}
+
+// taskTimeout 任务的执行超时时间，任务选项优先于全局配置
+func taskTimeout(options taskOptions) time.Duration {
+	if options.timeout > 0 {
+		return options.timeout
+	}
+	return time.Duration(global.Config().SCHEDULERTASKTIMEOUT) * time.Second
+}
+
+// recoverPanic 兜底捕获任务外围（抢锁、写执行记录等）的 panic，避免进程退出
+func recoverPanic(name string) {
+	if r := recover(); r != nil {
+		zap.L().Error("定时任务调度发生 panic",
+			zap.String("task", name),
+			zap.Any("panic", r),
+			zap.String("stack", string(debug.Stack())),
+		)
+	}
+}
+
+// safeRun 执行任务并捕获 panic，panic 和超时都视为执行失败
+func safeRun(ctx context.Context, task Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			zap.L().Error("定时任务发生 panic",
+				zap.String("task", task.Name()),
+				zap.Any("panic", r),
+				zap.String("stack", string(debug.Stack())),
+			)
+			err = fmt.Errorf("任务 panic: %v", r)
+		}
+	}()
+	err = task.Run(ctx)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		if err == nil {
+			err = ctx.Err()
+		}
+		err = fmt.Errorf("任务执行超时: %w", err)
+	}
+	return err
+}
+
+// recordFinishedRun 写入一条未实际执行的记录（跳过、抢锁失败等）
+func recordFinishedRun(name string, f fire, status, errText string) {
+	taskRunsTotal.WithLabelValues(name, status).Inc()
+	now := time.Now()
+	_, err := curd_methods.AddTaskRunRecord(curd_methods.YYMTaskRunRecord{
+		TaskName:  name,
+		EntryID:   int(f.entryID),
+		FireID:    f.fireID,
+		FireTime:  f.fireTime,
+		Attempt:   1,
+		Instance:  instanceID,
+		StartedAt: now,
+		EndedAt:   &now,
+		Status:    status,
+		ErrorText: errText,
+	})
+	if err != nil {
+		zap.L().Error("写入任务执行记录失败", zap.String("task", name), zap.Error(err))
+	}
+}
+
+// runWithHistory 执行一次任务并把开始/结束时间、状态、错误和处理条数写入执行记录表
+func runWithHistory(task Task, options taskOptions, f fire, attempt int) error {
+	name := task.Name()
+	stats := &runStats{}
+	ctx := context.WithValue(baseCtx, runStatsKey{}, stats)
+	ctx = context.WithValue(ctx, runInfoKey{}, RunInfo{
+		TaskName: name,
+		FireID:   f.fireID,
+		Attempt:  attempt,
+		Manual:   f.manual,
+	})
+	ctx = tools.WithTraceID(ctx, f.fireID)
+	if timeout := taskTimeout(options); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	startedAt := time.Now()
+	record, err := curd_methods.AddTaskRunRecord(curd_methods.YYMTaskRunRecord{
+		TaskName:  name,
+		EntryID:   int(f.entryID),
+		FireID:    f.fireID,
+		FireTime:  f.fireTime,
+		Attempt:   attempt,
+		Instance:  instanceID,
+		StartedAt: startedAt,
+		Status:    curd_methods.TaskRunStatusRunning,
+	})
+	if err != nil {
+		// 记录写入失败不影响任务本身执行
+		zap.L().Error("写入任务执行记录失败", zap.String("task", name), zap.Error(err))
+	}
+
+	jobErr := safeRun(ctx, task)
+
+	endedAt := time.Now()
+	status := curd_methods.TaskRunStatusSuccess
+	errText := ""
+	if jobErr != nil {
+		status = curd_methods.TaskRunStatusFailed
+		errText = jobErr.Error()
+		zap.L().Error("定时任务执行失败", zap.String("task", name), zap.Int("attempt", attempt), zap.Error(jobErr))
+	}
+	taskRunsTotal.WithLabelValues(name, status).Inc()
+	taskRunDuration.WithLabelValues(name).Observe(endedAt.Sub(startedAt).Seconds())
+	if record.ID == 0 {
+		return jobErr
+	}
+	err = curd_methods.FinishTaskRunRecord(record.ID, status, errText, stats.processed.Load(), endedAt, endedAt.Sub(startedAt))
+	if err != nil {
+		zap.L().Error("回写任务执行记录失败", zap.String("task", name), zap.Error(err))
+	}
+	return jobErr
+}
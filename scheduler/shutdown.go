@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"go-task-service/cmd/global"
+	"go.uber.org/zap"
+	"sync"
+	"sync/atomic"
+)
+
+var ErrShuttingDown = errors.New("调度器正在退出")
+
+var (
+	// baseCtx 所有任务执行的父 context，退出超时后取消，通知仍在运行的任务尽快结束
+	baseCtx, cancelBase = context.WithCancel(context.Background())
+	// running 正在执行的触发（包括手动触发）
+	running sync.WaitGroup
+	// shuttingDown 退出中不再接受新的触发
+	shuttingDown atomic.Bool
+	// runMu 保证 running.Add 不会与 Shutdown 中的 running.Wait 并发
+	runMu sync.Mutex
+)
+
+// beginRun 登记一次执行，退出中返回 false
+func beginRun() bool {
+	runMu.Lock()
+	defer runMu.Unlock()
+	if shuttingDown.Load() {
+		return false
+	}
+	running.Add(1)
+	return true
+}
+
+// Shutdown 停止调度并等待正在执行的任务结束，ctx 到期后取消任务 context 并返回 ctx.Err()
+func Shutdown(ctx context.Context) error {
+	runMu.Lock()
+	shuttingDown.Store(true)
+	runMu.Unlock()
+	stopTaskSchedules()
+	StopLeaderElection()
+	cronCtx := global.Cron.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		<-cronCtx.Done()
+		running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		zap.L().Info("定时任务已全部执行完成，调度器已停止")
+		return nil
+	case <-ctx.Done():
+		cancelBase()
+		zap.L().Warn("等待定时任务结束超时，已取消仍在执行的任务", zap.Error(ctx.Err()))
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Task 定时任务接口，所有任务都实现该接口并通过 Register 注册到调度器
+type Task interface {
+	// Name 任务唯一名称，用于执行记录和管理接口
+	Name() string
+	// Spec cron 表达式（带秒）
+	Spec() string
+	// Run 执行任务，返回错误时本次执行记为失败
+	Run(ctx context.Context) error
+}
+
+// funcTask 把普通函数包装成 Task
+type funcTask struct {
+	name string
+	spec string
+	run  func(ctx context.Context) error
+}
+
+func (t *funcTask) Name() string                  { return t.name }
+func (t *funcTask) Spec() string                  { return t.spec }
+func (t *funcTask) Run(ctx context.Context) error { return t.run(ctx) }
+
+// NewTask 用名称、cron 表达式和执行函数构造一个 Task
+func NewTask(name, spec string, run func(ctx context.Context) error) Task {
+	return &funcTask{name: name, spec: spec, run: run}
+}
+
+var ErrTaskExists = errors.New("任务名称已存在")
+
+// OverlapPolicy 上一次执行尚未结束时新触发的处理策略，按任务名在集群内通过 Redis 锁判断
+type OverlapPolicy int
+
+const (
+	// OverlapAllow 允许并发执行
+	OverlapAllow OverlapPolicy = iota
+	// OverlapSkip 上一次仍在执行时跳过本次触发
+	OverlapSkip
+	// OverlapDelay 等待上一次执行结束后再执行
+	OverlapDelay
+)
+
+func (p OverlapPolicy) String() string {
+	switch p {
+	case OverlapSkip:
+		return "skip"
+	case OverlapDelay:
+		return "delay"
+	default:
+		return "allow"
+	}
+}
+
+// taskOptions 注册任务时指定的执行选项
+type taskOptions struct {
+	overlap OverlapPolicy
+	timeout time.Duration
+	retry   RetryPolicy
+}
+
+// TaskOption 注册任务时的可选配置
+type TaskOption func(*taskOptions)
+
+// WithOverlapPolicy 设置任务的并发执行策略，默认 OverlapAllow
+func WithOverlapPolicy(policy OverlapPolicy) TaskOption {
+	return func(o *taskOptions) {
+		o.overlap = policy
+	}
+}
+
+// WithTimeout 设置任务单次执行的超时时间，超时后通过 ctx 通知任务退出。
+// 未设置时使用配置 SCHEDULER_TASK_TIMEOUT，两者都为 0 表示不限制
+func WithTimeout(timeout time.Duration) TaskOption {
+	return func(o *taskOptions) {
+		o.timeout = timeout
+	}
+}
+
+// registeredTask 注册表中的任务及其选项
+type registeredTask struct {
+	task    Task
+	options taskOptions
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*registeredTask)
+	// 保留注册顺序，保证调度器按固定顺序添加任务
+	registryOrder []string
+)
+
+// Register 注册一个定时任务，任务名称为空或重复时返回错误
+func Register(task Task, opts ...TaskOption) error {
+	if task == nil || task.Name() == "" {
+		return errors.New("任务名称不能为空")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[task.Name()]; ok {
+		return fmt.Errorf("%w: %s", ErrTaskExists, task.Name())
+	}
+	entry := &registeredTask{task: task}
+	for _, opt := range opts {
+		opt(&entry.options)
+	}
+	registry[task.Name()] = entry
+	registryOrder = append(registryOrder, task.Name())
+	return nil
+}
+
+// MustRegister 注册任务，失败直接 panic，供任务文件的 init 使用
+func MustRegister(task Task, opts ...TaskOption) {
+	if err := Register(task, opts...); err != nil {
+		panic("注册定时任务失败: " + err.Error())
+	}
+}
+
+// Tasks 按注册顺序返回所有已注册的任务
+func Tasks() []Task {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	tasks := make([]Task, 0, len(registryOrder))
+	for _, name := range registryOrder {
+		tasks = append(tasks, registry[name].task)
+	}
+	return tasks
+}
+
+// LookupTask 根据名称查找已注册的任务
+func LookupTask(name string) (Task, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	entry, ok := registry[name]
+	if !ok {
+		return nil, false
+	}
+	return entry.task, true
+}
+
+// lookupOptions 返回任务注册时的选项
+func lookupOptions(name string) taskOptions {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if entry, ok := registry[name]; ok {
+		return entry.options
+	}
+	return taskOptions{}
+}
@@ -4,37 +4,262 @@ import (
 	"context"
 	"fmt"
 	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/sbigtree/go-db-model/models"
 	"go-task-service/cmd/global"
+	"go-task-service/core/auxiliary_method"
 	"go-task-service/core/curd_methods"
+	"go-task-service/core/mq"
+	"go-task-service/core/repository/mongodb_methods"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 	"log"
-	"strconv"
+	"sync"
+	"time"
 )
 
-func UpdateInventoryTask() {
+const inventoryTaskName = "update_inventory"
+
+func init() {
+	MustRegister(NewTask(inventoryTaskName, "0 0 */6 * * *", UpdateInventoryTask),
+		WithOverlapPolicy(OverlapSkip),
+		WithTimeout(30*time.Minute),
+		WithRetry(RetryPolicy{
+			MaxAttempts:    4,
+			InitialBackoff: 30 * time.Second,
+			MaxBackoff:     5 * time.Minute,
+			Jitter:         0.2,
+			Retryable:      curd_methods.IsTransientDBError,
+		}),
+	)
+}
+
+func UpdateInventoryTask(ctx context.Context) error {
 	log.Println("[定时任务] 开始执行 UpdateInventoryTask")
 
-	//第一步查询六小时没有更新的库存信息 然后把他们的 steam_aid 发送到消息队列
-	inventoryList, err := curd_methods.QueryOutdatedInventory()
+	sweep := newInventorySweep()
+	//第一步优先重发上一次发送失败的账号
+	if err := sweep.publishFailed(ctx); err != nil {
+		zap.L().Error("重发上次失败的账号出错", zap.Error(err))
+	}
+	//第二步分页查询六小时没有更新的库存信息 然后把去重后的 steam_aid 发送到消息队列
+	err := curd_methods.QueryOutdatedInventory(ctx, global.Config().INVENTORYPAGESIZE, func(page []models.YYMBoxInventory) error {
+		sweep.rows += len(page)
+		return sweep.publishPage(ctx, page)
+	})
+	//等待所有在途消息完成，把最终失败的账号记录下来供下一次优先处理
+	summary := sweep.publisher.Wait()
+	if saveErr := auxiliary_method.SaveFailedInventoryRefresh(ctx, sweep.failedAIDs()); saveErr != nil {
+		zap.L().Error("记录发送失败的账号出错", zap.Error(saveErr))
+	}
+	outboxed := sweep.outboxedCount()
+	zap.L().Info("库存刷新扫描结束",
+		zap.Int("rows", sweep.rows),
+		zap.Int("accounts", len(sweep.seen)),
+		zap.Int("already_queued", sweep.alreadyQueued),
+		zap.Int("sent", summary.Sent),
+		zap.Int("failed", summary.Failed),
+		zap.Int("outboxed", outboxed),
+	)
+	if err != nil {
+		log.Println("处理过期库存失败:", err)
+		zap.L().Error("处理过期库存失败", zap.Error(err))
+		return fmt.Errorf("处理过期库存失败: %w", err)
+	}
+	// 已转入发件箱的消息会继续重试，不算本次执行失败
+	if failed := summary.Failed - outboxed; failed > 0 {
+		return fmt.Errorf("部分消息发送失败: 成功 %d 条，失败 %d 条", summary.Sent, failed)
+	}
+	log.Println("[定时任务] UpdateInventoryTask 执行完成")
+	return nil
+}
+
+// inventorySweep 一次扫描的状态，多行库存共用同一个 steam 账号时只发送一次
+type inventorySweep struct {
+	publisher *mq.Publisher
+	// seen 本次扫描已处理过的 steam 账号
+	seen          map[int64]struct{}
+	rows          int
+	alreadyQueued int
+	// queuedTTL 入队标记的有效期，见 inventoryQueuedTTL
+	queuedTTL time.Duration
+
+	mu     sync.Mutex
+	failed []int64
+	// outboxed 发送失败后转入发件箱重试的消息数
+	outboxed int
+}
+
+func newInventorySweep() *inventorySweep {
+	return &inventorySweep{
+		publisher: mq.NewPublisher(mq.PublishOptions{
+			Concurrency: global.Config().PUBLISHCONCURRENCY,
+			MaxAttempts: global.Config().PUBLISHMAXATTEMPTS,
+		}),
+		seen:      make(map[int64]struct{}),
+		queuedTTL: auxiliary_method.InventoryRefreshTTL(inventoryQueuedTTL(time.Now())),
+	}
+}
+
+// inventoryQueuedTTL 按 update_inventory 当前的 spec 计算入队标记的有效期：扫描间隔加上任务超时。
+// 本次和下一次扫描都可能在开始后的超时时间内才处理到同一个账号，标记要保留到下一次扫描处理完该账号，
+// 爬虫积压时才不会被重复入队。spec 通过管理接口或 nacos 修改后下一次扫描自动按新间隔计算
+func inventoryQueuedTTL(now time.Time) time.Duration {
+	interval := taskInterval(inventoryTaskName, now)
+	if interval <= 0 {
+		return 0
+	}
+	return interval + taskTimeout(lookupOptions(inventoryTaskName))
+}
+
+// publishFailed 重发上一次扫描中发送失败的账号，成功后从失败集合中移除
+func (s *inventorySweep) publishFailed(ctx context.Context) error {
+	steamAIDs, err := auxiliary_method.QueryFailedInventoryRefresh(ctx)
 	if err != nil {
-		log.Println("查询过期库存失败:", err)
-		zap.L().Error("查询过期库存失败", zap.Error(err))
-		return
-	}
-	fmt.Println("查询到的过期库存信息:", inventoryList)
-	//查询到之后将他们的 steam_aid 发送到消息队列
-	for _, v := range inventoryList {
-		//调用全局生产者向队列当中发送一条信息
-		_, err = global.RocketMQProducer.SendSync(context.Background(), &primitive.Message{
-			Topic: "inventory_desc",
-			Body:  []byte(strconv.Itoa(int(v.SteamAID))),
-		})
+		return err
+	}
+	if len(steamAIDs) > 0 {
+		zap.L().Info("优先重发上次发送失败的账号", zap.Int("count", len(steamAIDs)))
+	}
+	for _, steamAID := range steamAIDs {
+		s.seen[steamAID] = struct{}{}
+		// 失败的账号不论是否已有入队标记都重发，标记只用于拦截后续扫描
+		if _, err := auxiliary_method.MarkInventoryRefreshQueued(ctx, steamAID, s.queuedTTL); err != nil {
+			zap.L().Error("写入库存刷新入队标记失败", zap.Int64("steam_aid", steamAID), zap.Error(err))
+		}
+		if err := s.publish(ctx, steamAID, mq.ReasonRetry, mq.PriorityHigh, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishPage 把一页库存中尚未入队的 steam 账号发送到消息队列
+func (s *inventorySweep) publishPage(ctx context.Context, page []models.YYMBoxInventory) error {
+	reason, priority := refreshReason(ctx)
+	for _, v := range page {
+		steamAID := int64(v.SteamAID)
+		if steamAID == 0 {
+			continue
+		}
+		if _, ok := s.seen[steamAID]; ok {
+			continue
+		}
+		s.seen[steamAID] = struct{}{}
+
+		// 账号已在刷新队列中且未过期，不重复入队
+		queued, err := auxiliary_method.MarkInventoryRefreshQueued(ctx, steamAID, s.queuedTTL)
 		if err != nil {
-			fmt.Println("发送消息失败", err)
-			zap.S().Error("发送消息失败", err)
+			// Redis 异常时仍然发送，最多造成一次重复刷新
+			zap.L().Error("写入库存刷新入队标记失败", zap.Int64("steam_aid", steamAID), zap.Error(err))
+		} else if !queued {
+			s.alreadyQueued++
+			continue
+		}
+
+		if err := s.publish(ctx, steamAID, reason, priority, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publish 异步发送一条库存刷新消息，最终失败时清除入队标记并记录到失败列表。
+// retrying 表示该账号是上一次失败后的重发，再次失败时写入死信，不再留在失败列表
+func (s *inventorySweep) publish(ctx context.Context, steamAID int64, reason string, priority int, retrying bool) error {
+	envelope, err := mq.NewInventoryRefreshMessage(ctx, steamAID, reason, priority)
+	if err != nil {
+		s.clearQueued(ctx, steamAID)
+		return err
+	}
+	msg, err := envelope.ToMessage()
+	if err != nil {
+		s.clearQueued(ctx, steamAID)
+		return err
+	}
+	err = s.publisher.Publish(ctx, msg, func(err error, attempts int) {
+		if err == nil {
+			AddProcessed(ctx, 1)
+			if retrying {
+				s.removeFailed(ctx, steamAID)
+			}
+			return
+		}
+		zap.L().Error("库存刷新消息发送失败", zap.Int64("steam_aid", steamAID), zap.Error(err))
+		// 转入发件箱后由投递协程持久化重试，超过次数后写入死信，入队标记保留到刷新完成
+		outboxErr := enqueueOutbox(ctx, msg)
+		if outboxErr == nil {
+			zap.L().Info("库存刷新消息已转入发件箱重试", zap.Int64("steam_aid", steamAID))
+			if retrying {
+				s.removeFailed(ctx, steamAID)
+			}
+			s.mu.Lock()
+			s.outboxed++
+			s.mu.Unlock()
 			return
 		}
-		fmt.Println("消息发送成功")
+		zap.L().Error("库存刷新消息写入发件箱失败", zap.Int64("steam_aid", steamAID), zap.Error(outboxErr))
+		s.clearQueued(ctx, steamAID)
+		if retrying {
+			dlqErr := mq.SaveDeadLetter(ctx, mongodb_methods.DeadLetterSourceProducer, msg, "", err, attempts)
+			if dlqErr == nil {
+				s.removeFailed(ctx, steamAID)
+				return
+			}
+			// 写入死信失败时留在失败列表，下一次扫描继续重发
+			zap.L().Error("写入死信失败", zap.Int64("steam_aid", steamAID), zap.Error(dlqErr))
+		}
+		s.mu.Lock()
+		s.failed = append(s.failed, steamAID)
+		s.mu.Unlock()
+	})
+	if err != nil {
+		s.clearQueued(ctx, steamAID)
+	}
+	return err
+}
+
+// enqueueOutbox 把发送失败的消息写入发件箱。回调时任务的 ctx 可能已超时，写入不受其影响
+func enqueueOutbox(ctx context.Context, msg *primitive.Message) error {
+	return curd_methods.ExecuteTransaction(context.WithoutCancel(ctx), func(tx *gorm.DB) error {
+		return mq.EnqueueOutbox(tx, msg)
+	})
+}
+
+// removeFailed 把账号从失败列表中移除
+func (s *inventorySweep) removeFailed(ctx context.Context, steamAID int64) {
+	if err := auxiliary_method.RemoveFailedInventoryRefresh(ctx, steamAID); err != nil {
+		zap.L().Error("移除发送失败账号出错", zap.Int64("steam_aid", steamAID), zap.Error(err))
+	}
+}
+
+// clearQueued 清除入队标记，下一次扫描可以重新入队
+func (s *inventorySweep) clearQueued(ctx context.Context, steamAID int64) {
+	if err := auxiliary_method.ClearInventoryRefreshQueued(ctx, steamAID); err != nil {
+		zap.L().Error("清除库存刷新入队标记失败", zap.Int64("steam_aid", steamAID), zap.Error(err))
+	}
+}
+
+func (s *inventorySweep) outboxedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.outboxed
+}
+
+func (s *inventorySweep) failedAIDs() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int64(nil), s.failed...)
+}
+
+// refreshReason 根据触发方式确定刷新原因和优先级
+func refreshReason(ctx context.Context) (string, int) {
+	info, ok := RunInfoFromContext(ctx)
+	switch {
+	case ok && info.Manual:
+		return mq.ReasonManual, mq.PriorityHigh
+	case ok && info.Attempt > 1:
+		return mq.ReasonRetry, mq.PriorityNormal
+	default:
+		return mq.ReasonScheduled, mq.PriorityNormal
 	}
-	log.Println("[定时任务] UpdateInventoryTask 执行完成")
 }